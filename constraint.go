@@ -1,6 +1,7 @@
 package semver
 
 import (
+	"fmt"
 	"strings"
 )
 
@@ -11,38 +12,568 @@ const (
 	ConstraintUnionAnd
 )
 
+// PrereleasePolicy defines which pre-release versions a constraint matches.
+type PrereleasePolicy uint8
+
+const (
+	// PrereleaseSameTuple excludes pre-release versions unless the
+	// constraint mentions a pre-release of the same major.minor.patch tuple:
+	// `^1.2.0` does not match `1.5.0-alpha`, while `>=1.5.0-alpha, <2.0.0`
+	// does. Unlike in npm and Cargo the tuple may be mentioned in any `||`
+	// alternative, not only in the matching one. This is the default.
+	PrereleaseSameTuple PrereleasePolicy = iota
+	// PrereleaseExclude never matches pre-release versions.
+	PrereleaseExclude
+	// PrereleaseInclude matches pre-release versions by their precedence
+	// only.
+	PrereleaseInclude
+)
+
 type Constraint struct {
-	left  Checker
-	right Checker
-	un    ConstraintUnion
+	left    Checker
+	right   Checker
+	un      ConstraintUnion
+	src     string
+	policy  PrereleasePolicy
+	dialect Dialect
+	// ivs are the versions matched by the tree, valid if compiled
+	ivs      []Interval
+	compiled bool
 }
 
 var _ Checker = (*Constraint)(nil)
 
-func NewConstraint(s string) (*Constraint, error) {
-	ors := strings.Split(s, "||")
+type constraintConfig struct {
+	policy  PrereleasePolicy
+	dialect Dialect
+}
+
+// ConstraintOption configures NewConstraint.
+type ConstraintOption func(*constraintConfig)
+
+// WithPrereleasePolicy sets the policy of matching pre-release versions.
+func WithPrereleasePolicy(p PrereleasePolicy) ConstraintOption {
+	return func(cfg *constraintConfig) {
+		cfg.policy = p
+	}
+}
+
+func NewConstraint(s string, opts ...ConstraintOption) (*Constraint, error) {
+	var cfg constraintConfig
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	if int(cfg.dialect) >= len(dialects) {
+		return nil, fmt.Errorf("%w: unknown dialect %d", ErrInvalidConstraint, cfg.dialect)
+	}
+	d := dialects[cfg.dialect]
+
+	ors, offs := splitOr(s, d.ors)
 	orConstr := make([]*Constraint, 0, len(ors))
-	for _, or := range ors {
-		ands := strings.Split(or, ",")
-		andConstr := make([]*Constraint, 0, len(ands))
-		for _, and := range ands {
-			c, err := parseConstraint(and)
+	for k, or := range ors {
+		andConstr, err := parseAnds(or, d)
+		if err != nil {
+			return nil, rebaseError(err, s, offs[k])
+		}
+		orConstr = append(orConstr, compact(andConstr, ConstraintUnionAnd))
+	}
+	c := compact(orConstr, ConstraintUnionOr)
+	c.src = s
+	c.policy, c.dialect = cfg.policy, cfg.dialect
+	c.ivs, c.compiled = checkerIntervals(c), true
+	return c, nil
+}
+
+// splitOr splits a constraint into alternatives on any of the separators,
+// trying them in order. Returns the alternatives and their start positions.
+func splitOr(s string, seps []string) ([]string, []int) {
+	var ors []string
+	var offs []int
+	i := 0
+	for k := 0; k < len(s); {
+		n := 0
+		for _, sep := range seps {
+			if strings.HasPrefix(s[k:], sep) {
+				n = len(sep)
+				break
+			}
+		}
+		if n == 0 {
+			k++
+			continue
+		}
+		ors, offs = append(ors, s[i:k]), append(offs, i)
+		k += n
+		i = k
+	}
+	return append(ors, s[i:]), append(offs, i)
+}
+
+// parseAnds parses comparators joined with commas or whitespace:
+// `>=1.2.0 <2.0.0, !=1.5.0`. An operator may be separated from its version
+// with whitespace, and a dash surrounded by whitespace joins 2 versions into
+// a hyphen range. The dialect d tells which of these are allowed.
+func parseAnds(s string, d *dialect) ([]*Constraint, error) {
+	var cs []*Constraint
+	parts := []string{s}
+	if d.commaAnd {
+		parts = strings.Split(s, ",")
+	}
+	off := 0
+	for _, part := range parts {
+		cmps := splitComparators(part, d.hyphen)
+		if len(cmps) == 0 {
+			// A blank constraint matches any version
+			cmps = []comparator{{i: 0, j: len(part), dash: -1}}
+		}
+		if len(cmps) > 1 && !d.spaceAnd {
+			err := newParseError(ErrInvalidConstraint, part, cmps[1].i, cmps[1].j, ReasonUnexpectedChar)
+			return nil, rebaseError(err, s, off)
+		}
+		for _, cmp := range cmps {
+			var c *Constraint
+			var err error
+			if cmp.dash >= 0 {
+				c, err = parseHyphenRange(part[cmp.i:cmp.j], cmp.dash-cmp.i, d)
+			} else {
+				c, err = parseConstraint(part[cmp.i:cmp.j], d)
+			}
 			if err != nil {
-				return nil, err
+				return nil, rebaseError(err, s, off+cmp.i)
 			}
-			andConstr = append(andConstr, c)
+			cs = append(cs, c)
 		}
-		orConstr = append(orConstr, compact(andConstr, ConstraintUnionAnd))
+		off += len(part) + len(",")
+	}
+	return cs, nil
+}
+
+// comparator is a span [i, j) of a single comparator in a conjunction. dash
+// is the position of the dash of a hyphen range or -1.
+type comparator struct {
+	i, j, dash int
+}
+
+// splitComparators splits a comma-free conjunction on whitespace. A field
+// consisting of operator characters only is merged with the following one:
+// `>= 1.2.0`, a standalone dash merges the surrounding fields into a hyphen
+// range: `1.2.3 - 2.3.4` unless hyphen ranges are disabled.
+func splitComparators(s string, hyphen bool) []comparator {
+	var cmps []comparator
+	var i, j int
+	for {
+		i, j = readField(s, i)
+		if i == j {
+			return cmps
+		}
+		switch {
+		case hyphen && j-i == 1 && isDash(s[i]) && len(cmps) > 0 && cmps[len(cmps)-1].dash < 0:
+			if st, k := readField(s, j); k > st {
+				last := &cmps[len(cmps)-1]
+				last.j, last.dash = k, i
+				j = k
+				break
+			}
+			cmps = append(cmps, comparator{i: i, j: j, dash: -1})
+		case isOpStr(s[i:j]):
+			if st, k := readField(s, j); k > st {
+				j = k
+			}
+			cmps = append(cmps, comparator{i: i, j: j, dash: -1})
+		default:
+			cmps = append(cmps, comparator{i: i, j: j, dash: -1})
+		}
+		i = j
+	}
+}
+
+// rebaseError makes a parse error of a constraint substring starting at
+// position off refer to the entire constraint s.
+func rebaseError(err error, s string, off int) error {
+	if perr, ok := err.(*ParseError); ok {
+		perr.Input = s
+		perr.Offset += off
+	}
+	return err
+}
+
+// Original returns the expression the constraint was created from, or an
+// empty string if it was not created by NewConstraint.
+func (c *Constraint) Original() string {
+	return c.src
+}
+
+// MarshalText implements encoding.TextMarshaler, the constraint is encoded as
+// written. A constraint not created by NewConstraint is encoded in the
+// normalized range form, see String.
+func (c *Constraint) MarshalText() ([]byte, error) {
+	if c.src != "" {
+		return []byte(c.src), nil
 	}
-	return compact(orConstr, ConstraintUnionOr), nil
+	return []byte(c.String()), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler, the constraint is decoded
+// with NewConstraint keeping the pre-release policy and the dialect of c.
+// Neither of them is a part of the encoding.
+func (c *Constraint) UnmarshalText(b []byte) error {
+	nc, err := NewConstraint(string(b), WithPrereleasePolicy(c.policy), WithDialect(c.dialect))
+	if err != nil {
+		return err
+	}
+	*c = *nc
+	return nil
+}
+
+// NormalizedConstraint encodes a constraint in the normalized range form
+// rather than as written: `~1.2` is encoded as `>=1.2.0, <1.3.0`. The zero
+// value holding no constraint is encoded as an empty string.
+type NormalizedConstraint struct {
+	*Constraint
+}
+
+func (n NormalizedConstraint) MarshalText() ([]byte, error) {
+	if n.Constraint == nil {
+		return []byte{}, nil
+	}
+	return []byte(n.String()), nil
+}
+
+// UnmarshalText decodes a constraint keeping the pre-release policy of the
+// current one, if any. The normalized form is always in the default dialect.
+// An empty string decodes into no constraint.
+func (n *NormalizedConstraint) UnmarshalText(b []byte) error {
+	if len(b) == 0 {
+		n.Constraint = nil
+		return nil
+	}
+	var c Constraint
+	if n.Constraint != nil {
+		c.policy = n.policy
+	}
+	if err := c.UnmarshalText(b); err != nil {
+		return err
+	}
+	n.Constraint = &c
+	return nil
+}
+
+// String renders the constraint in the normalized range form: the matched
+// versions as sorted disjoint ranges of guards joined with `,`, the ranges
+// joined with `||`: `^0.2 || 1.x` is rendered as
+// `>=0.2.0, <0.3.0 || >=1.0.0, <2.0.0`.
+func (c *Constraint) String() string {
+	rs, _, bases := c.simplified()
+	return renderRanges(rs, bases, false)
+}
+
+// Simplify returns the shortest constraint equivalent to c: redundant guards
+// are dropped and overlapping ranges are merged, so
+// `>=1.0.0, >=1.2.0, <3.0.0, <2.5.0 || ^1.4` turns into `>=1.2.0, <2.5.0`.
+// Returns c itself if it has no more guards than the simplified constraint.
+func (c *Constraint) Simplify() *Constraint {
+	rs, ivs, bases := c.simplified()
+	sc := intervalsConstraint(rs, bases)
+	if countGuards(sc) >= countGuards(c) {
+		return c
+	}
+	sc.ivs, sc.policy = ivs, c.policy
+	return sc
+}
+
+// simplified returns the ranges to render the simplified constraint with, the
+// intervals they match and the pre-release tuples the ranges refer to under
+// PrereleaseSameTuple, see sameTupleRanges.
+func (c *Constraint) simplified() ([]Interval, []Interval, []uint64) {
+	ivs := c.intervals()
+	if c.policy != PrereleaseSameTuple {
+		return ivs, ivs, nil
+	}
+	bases := c.preTuples(nil)
+	rs, ivs := sameTupleRanges(ivs, bases)
+	return rs, ivs, bases
+}
+
+// countGuards returns the number of guards of a constraint tree.
+func countGuards(ch Checker) int {
+	switch n := ch.(type) {
+	case *Guard:
+		if n != nil {
+			return 1
+		}
+	case *Constraint:
+		if n != nil {
+			return countGuards(n.left) + countGuards(n.right)
+		}
+	}
+	return 0
+}
+
+// Sugared renders the simplified constraint like String does, using the `^`
+// and `~` operators where they are exactly equivalent to a range:
+// `>=1.2.0, <2.0.0 || >=3.1.0, <3.2.0` is rendered as `^1.2.0 || ~3.1.0`.
+func (c *Constraint) Sugared() string {
+	rs, _, bases := c.simplified()
+	return renderRanges(rs, bases, true)
+}
+
+// renderRanges renders the ranges joined with `||`, see intervalGuards for
+// bases. A range is rendered with `^` or `~` if sugar is set and either of
+// them is exact, see sugarInterval.
+func renderRanges(rs []Interval, bases []uint64, sugar bool) string {
+	if len(rs) == 0 {
+		return "<0.0.0-0"
+	}
+	var b strings.Builder
+	for i, r := range rs {
+		if i > 0 {
+			b.WriteString(" || ")
+		}
+		if s := sugarInterval(r); sugar && s != "" {
+			b.WriteString(s)
+			continue
+		}
+		for j, g := range intervalGuards(r, bases) {
+			if j > 0 {
+				b.WriteString(", ")
+			}
+			b.WriteString(g.String())
+		}
+	}
+	return b.String()
+}
+
+// Intersect returns a normalized constraint matching the versions matched by
+// both a and b, and whether there is any such version. The pre-release
+// policies of a and b both apply: the result matches the pre-releases of a
+// tuple only if both a and b do.
+func Intersect(a, b *Constraint) (*Constraint, bool) {
+	ivs := intersectIntervals(a.intervals(), b.intervals())
+	var c *Constraint
+	switch {
+	case b.policy == PrereleaseInclude:
+		c = policyConstraint(ivs, a.policy, policyTuples(a))
+	case a.policy == PrereleaseInclude:
+		c = policyConstraint(ivs, b.policy, policyTuples(b))
+	case a.policy == PrereleaseExclude || b.policy == PrereleaseExclude:
+		c = policyConstraint(ivs, PrereleaseExclude, nil)
+	default:
+		ta, tb := policyTuples(a), policyTuples(b)
+		var bases []uint64
+		for _, base := range ta {
+			if containsBase(tb, base) {
+				bases = append(bases, base)
+			}
+		}
+		c = policyConstraint(ivs, PrereleaseSameTuple, bases)
+	}
+	return c, len(c.ivs) > 0
+}
+
+// policyTuples returns the pre-release tuples c matches pre-releases of, see
+// PrereleaseSameTuple. It is nil unless the policy of c is
+// PrereleaseSameTuple.
+func policyTuples(c *Constraint) []uint64 {
+	if c.policy != PrereleaseSameTuple {
+		return nil
+	}
+	return c.preTuples(nil)
+}
+
+// policyConstraint builds a normalized constraint with the policy matching
+// the versions of the intervals. Under PrereleaseSameTuple these are the
+// releases and the pre-releases of the tuples bases, see sameTupleRanges.
+// The intervals matching no version under the policy are dropped.
+func policyConstraint(ivs []Interval, policy PrereleasePolicy, bases []uint64) *Constraint {
+	rs := ivs
+	switch policy {
+	case PrereleaseExclude:
+		rs, ivs = sameTupleRanges(ivs, nil)
+		bases = nil
+	case PrereleaseSameTuple:
+		rs, ivs = sameTupleRanges(ivs, bases)
+	default:
+		bases = nil
+	}
+	c := intervalsConstraint(rs, bases)
+	c.ivs, c.policy = ivs, policy
+	return c
+}
+
+// Union returns a normalized constraint matching the versions matched by
+// either a or b. The pre-release policies of a and b both apply: the result
+// matches the pre-releases either of them does. If only one of them has the
+// PrereleaseInclude policy, which no single policy can combine with another
+// one, the result includes all the pre-releases of the other one as well.
+func Union(a, b *Constraint) *Constraint {
+	ia, ib := a.intervals(), b.intervals()
+	switch {
+	case a.policy == PrereleaseInclude || b.policy == PrereleaseInclude:
+		return policyConstraint(unionIntervals(ia, ib), PrereleaseInclude, nil)
+	case a.policy == PrereleaseExclude && b.policy == PrereleaseExclude:
+		return policyConstraint(unionIntervals(ia, ib), PrereleaseExclude, nil)
+	}
+	// The pre-releases of a tuple only one of them refers to come from it
+	ta, tb := policyTuples(a), policyTuples(b)
+	var onlyA, onlyB []uint64
+	for _, base := range ta {
+		if !containsBase(tb, base) {
+			onlyA = append(onlyA, base)
+		}
+	}
+	for _, base := range tb {
+		if !containsBase(ta, base) {
+			onlyB = append(onlyB, base)
+		}
+	}
+	ivs := unionIntervals(cutPrereleases(ia, onlyB), cutPrereleases(ib, onlyA))
+	return policyConstraint(ivs, PrereleaseSameTuple, append(ta, onlyB...))
+}
+
+// Not returns a normalized constraint matching the versions not matched by c:
+// `^1.2` turns into `<1.2.0 || >=2.0.0`. The pre-release policy of c applies
+// to the result, so it matches no pre-release c can not match by its policy.
+func Not(c *Constraint) *Constraint {
+	return policyConstraint(complementIntervals(c.intervals()), c.policy, policyTuples(c))
+}
+
+// intervals returns the compiled intervals of the constraint or computes them
+// for a constraint built without NewConstraint.
+func (c *Constraint) intervals() []Interval {
+	if c.compiled {
+		return c.ivs
+	}
+	return checkerIntervals(c)
+}
+
+// Intervals returns the sorted disjoint intervals of versions matched by the
+// constraint regardless of its pre-release policy.
+func (c *Constraint) Intervals() []Interval {
+	ivs := c.intervals()
+	res := make([]Interval, len(ivs))
+	for i, iv := range ivs {
+		if iv.Hi.isInf() {
+			iv.Hi = nil
+		}
+		res[i] = iv
+	}
+	return res
+}
+
+// IsSubsetOf tells whether every version matched by c is matched by other.
+// Like the rest of the set operations it ignores the pre-release policies.
+func (c *Constraint) IsSubsetOf(other *Constraint) bool {
+	return len(intersectIntervals(c.intervals(), complementIntervals(other.intervals()))) == 0
+}
+
+// Equivalent tells whether c and other match the same versions: `~1.2` and
+// `>=1.2.0, <1.3.0` are equivalent.
+func (c *Constraint) Equivalent(other *Constraint) bool {
+	return equalIntervals(c.intervals(), other.intervals())
+}
+
+// IsEmpty tells whether c matches no version: `>2.0.0, <1.0.0`.
+func (c *Constraint) IsEmpty() bool {
+	return len(c.intervals()) == 0
+}
+
+// IsAny tells whether c matches every version `*` does, i.e. `0.0.0` and
+// every following version.
+func (c *Constraint) IsAny() bool {
+	ivs := c.intervals()
+	return len(ivs) > 0 && !verZero.Less(ivs[len(ivs)-1].Lo) && ivs[len(ivs)-1].Hi.isInf()
+}
+
+func (c *Constraint) PrereleasePolicy() PrereleasePolicy {
+	return c.policy
 }
 
 func (c *Constraint) Check(v *Version) bool {
+	if len(v.pre) > 0 {
+		switch c.policy {
+		case PrereleaseExclude:
+			return false
+		case PrereleaseSameTuple:
+			if !c.hasPreTuple(v.base) {
+				return false
+			}
+		}
+	}
+	if c.compiled {
+		return searchIntervals(c.ivs, v)
+	}
+	return c.check(v)
+}
+
+func (c *Constraint) check(v *Version) bool {
 	switch c.un {
 	case ConstraintUnionAnd:
-		return c.left.Check(v) && c.right.Check(v)
+		return checkNode(c.left, v) && checkNode(c.right, v)
 	case ConstraintUnionOr:
-		return c.left.Check(v) || c.right.Check(v)
+		return checkNode(c.left, v) || checkNode(c.right, v)
 	}
 	panic("should not happen")
 }
+
+// MaxSatisfying returns the greatest of the versions matched by c, or nil if
+// there is none.
+func (c *Constraint) MaxSatisfying(vs []*Version) *Version {
+	var max *Version
+	for _, v := range vs {
+		if (max == nil || max.Less(v)) && c.Check(v) {
+			max = v
+		}
+	}
+	return max
+}
+
+// MinSatisfying returns the least of the versions matched by c, or nil if
+// there is none.
+func (c *Constraint) MinSatisfying(vs []*Version) *Version {
+	var min *Version
+	for _, v := range vs {
+		if (min == nil || v.Less(min)) && c.Check(v) {
+			min = v
+		}
+	}
+	return min
+}
+
+// FilterSatisfying returns the versions matched by c keeping their order.
+func (c *Constraint) FilterSatisfying(vs []*Version) []*Version {
+	var res []*Version
+	for _, v := range vs {
+		if c.Check(v) {
+			res = append(res, v)
+		}
+	}
+	return res
+}
+
+// checkNode checks a version against a constraint tree node. The pre-release
+// policy only applies at the root of the tree.
+func checkNode(ch Checker, v *Version) bool {
+	if c, ok := ch.(*Constraint); ok {
+		return c.check(v)
+	}
+	return ch.Check(v)
+}
+
+// hasPreTuple tells whether any guard of the constraint tree refers to a
+// pre-release version with the given major.minor.patch tuple.
+func (c *Constraint) hasPreTuple(base uint64) bool {
+	for _, ch := range [...]Checker{c.left, c.right} {
+		switch n := ch.(type) {
+		case *Guard:
+			if n != nil && len(n.ver.pre) > 0 && n.ver.base == base {
+				return true
+			}
+		case *Constraint:
+			if n.hasPreTuple(base) {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -1,9 +1,12 @@
 package semver
 
 import (
+	"encoding/json"
+	"errors"
 	"fmt"
 	"math/rand"
 	"reflect"
+	"strings"
 	"testing"
 	"time"
 
@@ -313,7 +316,7 @@ func TestParseConstraint(t *testing.T) {
 			Input: "1.2.3",
 			ExpectConstr: &Constraint{
 				left: NewGuard(
-					NewVersionRaw([]uint32{1, 2, 3}, ""),
+					newVersionRaw([]uint32{1, 2, 3}, ""),
 					GuardEqual,
 				),
 				right: (*Guard)(nil),
@@ -324,11 +327,11 @@ func TestParseConstraint(t *testing.T) {
 			Input: "1.2.*",
 			ExpectConstr: &Constraint{
 				left: NewGuard(
-					NewVersionRaw([]uint32{1, 2, 0}, ""),
+					newVersionRaw([]uint32{1, 2, 0}, ""),
 					GuardGreaterOrEqual,
 				),
 				right: NewGuard(
-					NewVersionRaw([]uint32{1, 3, 0}, ""),
+					newVersionRaw([]uint32{1, 3, 0}, ""),
 					GuardLessThan,
 				),
 				un: ConstraintUnionAnd,
@@ -338,11 +341,11 @@ func TestParseConstraint(t *testing.T) {
 			Input: "1.*",
 			ExpectConstr: &Constraint{
 				left: NewGuard(
-					NewVersionRaw([]uint32{1, 0, 0}, ""),
+					newVersionRaw([]uint32{1, 0, 0}, ""),
 					GuardGreaterOrEqual,
 				),
 				right: NewGuard(
-					NewVersionRaw([]uint32{2, 0, 0}, ""),
+					newVersionRaw([]uint32{2, 0, 0}, ""),
 					GuardLessThan,
 				),
 				un: ConstraintUnionAnd,
@@ -352,11 +355,11 @@ func TestParseConstraint(t *testing.T) {
 			Input: "*",
 			ExpectConstr: &Constraint{
 				left: NewGuard(
-					NewVersionRaw([]uint32{0, 0, 0}, ""),
+					newVersionRaw([]uint32{0, 0, 0}, ""),
 					GuardGreaterOrEqual,
 				),
 				right: NewGuard(
-					&Version{base: 0x3FFFFFFF + 1},
+					&Version{base: baseInf},
 					GuardLessThan,
 				),
 				un: ConstraintUnionAnd,
@@ -366,11 +369,11 @@ func TestParseConstraint(t *testing.T) {
 			Input: "*.*",
 			ExpectConstr: &Constraint{
 				left: NewGuard(
-					NewVersionRaw([]uint32{0, 0, 0}, ""),
+					newVersionRaw([]uint32{0, 0, 0}, ""),
 					GuardGreaterOrEqual,
 				),
 				right: NewGuard(
-					&Version{base: 0x3FFFFFFF + 1},
+					&Version{base: baseInf},
 					GuardLessThan,
 				),
 				un: ConstraintUnionAnd,
@@ -380,11 +383,11 @@ func TestParseConstraint(t *testing.T) {
 			Input: "*.*.*",
 			ExpectConstr: &Constraint{
 				left: NewGuard(
-					NewVersionRaw([]uint32{0, 0, 0}, ""),
+					newVersionRaw([]uint32{0, 0, 0}, ""),
 					GuardGreaterOrEqual,
 				),
 				right: NewGuard(
-					&Version{base: 0x3FFFFFFF + 1},
+					&Version{base: baseInf},
 					GuardLessThan,
 				),
 				un: ConstraintUnionAnd,
@@ -394,7 +397,7 @@ func TestParseConstraint(t *testing.T) {
 			Input: "=1.2.3-beta.0",
 			ExpectConstr: &Constraint{
 				left: NewGuard(
-					NewVersionRaw([]uint32{1, 2, 3}, "beta.0"),
+					newVersionRaw([]uint32{1, 2, 3}, "beta.0"),
 					GuardEqual,
 				),
 				right: (*Guard)(nil),
@@ -405,7 +408,7 @@ func TestParseConstraint(t *testing.T) {
 			Input: "=v1.2.3-beta.0",
 			ExpectConstr: &Constraint{
 				left: NewGuard(
-					NewVersionRaw([]uint32{1, 2, 3}, "beta.0"),
+					newVersionRaw([]uint32{1, 2, 3}, "beta.0"),
 					GuardEqual,
 				),
 				right: (*Guard)(nil),
@@ -416,7 +419,18 @@ func TestParseConstraint(t *testing.T) {
 			Input: "= v1.2.3-beta.0",
 			ExpectConstr: &Constraint{
 				left: NewGuard(
-					NewVersionRaw([]uint32{1, 2, 3}, "beta.0"),
+					newVersionRaw([]uint32{1, 2, 3}, "beta.0"),
+					GuardEqual,
+				),
+				right: (*Guard)(nil),
+				un:    ConstraintUnionOr,
+			},
+		},
+		{
+			Input: "=1.2.3-beta.0+build.5",
+			ExpectConstr: &Constraint{
+				left: NewGuard(
+					newVersionRaw([]uint32{1, 2, 3}, "beta.0"),
 					GuardEqual,
 				),
 				right: (*Guard)(nil),
@@ -427,11 +441,11 @@ func TestParseConstraint(t *testing.T) {
 			Input: "!=1.2.3-beta.0",
 			ExpectConstr: &Constraint{
 				left: NewGuard(
-					NewVersionRaw([]uint32{1, 2, 3}, "beta.0"),
+					newVersionRaw([]uint32{1, 2, 3}, "beta.0"),
 					GuardLessThan,
 				),
 				right: NewGuard(
-					NewVersionRaw([]uint32{1, 2, 3}, "beta.0"),
+					newVersionRaw([]uint32{1, 2, 3}, "beta.0"),
 					GuardGreaterThan,
 				),
 				un: ConstraintUnionOr,
@@ -441,12 +455,12 @@ func TestParseConstraint(t *testing.T) {
 			Input: "!=1.2.*",
 			ExpectConstr: &Constraint{
 				left: NewGuard(
-					NewVersionRaw([]uint32{1, 2, 0}, ""),
+					newVersionRaw([]uint32{1, 2, 0}, ""),
 					GuardLessThan,
 				),
 				right: NewGuard(
-					NewVersionRaw([]uint32{1, 3, 0}, ""),
-					GuardGreaterThan,
+					newVersionRaw([]uint32{1, 3, 0}, ""),
+					GuardGreaterOrEqual,
 				),
 				un: ConstraintUnionOr,
 			},
@@ -455,12 +469,12 @@ func TestParseConstraint(t *testing.T) {
 			Input: "!=1.2",
 			ExpectConstr: &Constraint{
 				left: NewGuard(
-					NewVersionRaw([]uint32{1, 2, 0}, ""),
+					newVersionRaw([]uint32{1, 2, 0}, ""),
 					GuardLessThan,
 				),
 				right: NewGuard(
-					NewVersionRaw([]uint32{1, 3, 0}, ""),
-					GuardGreaterThan,
+					newVersionRaw([]uint32{1, 3, 0}, ""),
+					GuardGreaterOrEqual,
 				),
 				un: ConstraintUnionOr,
 			},
@@ -469,12 +483,12 @@ func TestParseConstraint(t *testing.T) {
 			Input: "!=1",
 			ExpectConstr: &Constraint{
 				left: NewGuard(
-					NewVersionRaw([]uint32{1, 0, 0}, ""),
+					newVersionRaw([]uint32{1, 0, 0}, ""),
 					GuardLessThan,
 				),
 				right: NewGuard(
-					NewVersionRaw([]uint32{2, 0, 0}, ""),
-					GuardGreaterThan,
+					newVersionRaw([]uint32{2, 0, 0}, ""),
+					GuardGreaterOrEqual,
 				),
 				un: ConstraintUnionOr,
 			},
@@ -483,7 +497,7 @@ func TestParseConstraint(t *testing.T) {
 			Input: ">1.2.3-beta.0",
 			ExpectConstr: &Constraint{
 				left: NewGuard(
-					NewVersionRaw([]uint32{1, 2, 3}, "beta.0"),
+					newVersionRaw([]uint32{1, 2, 3}, "beta.0"),
 					GuardGreaterThan,
 				),
 				right: (*Guard)(nil),
@@ -494,7 +508,7 @@ func TestParseConstraint(t *testing.T) {
 			Input: ">1.2.3",
 			ExpectConstr: &Constraint{
 				left: NewGuard(
-					NewVersionRaw([]uint32{1, 2, 3}, ""),
+					newVersionRaw([]uint32{1, 2, 3}, ""),
 					GuardGreaterThan,
 				),
 				right: (*Guard)(nil),
@@ -505,7 +519,7 @@ func TestParseConstraint(t *testing.T) {
 			Input: ">1.2",
 			ExpectConstr: &Constraint{
 				left: NewGuard(
-					NewVersionRaw([]uint32{1, 3, 0}, ""),
+					newVersionRaw([]uint32{1, 3, 0}, ""),
 					GuardGreaterOrEqual,
 				),
 				right: (*Guard)(nil),
@@ -516,7 +530,7 @@ func TestParseConstraint(t *testing.T) {
 			Input: ">1",
 			ExpectConstr: &Constraint{
 				left: NewGuard(
-					NewVersionRaw([]uint32{2, 0, 0}, ""),
+					newVersionRaw([]uint32{2, 0, 0}, ""),
 					GuardGreaterOrEqual,
 				),
 				right: (*Guard)(nil),
@@ -527,7 +541,7 @@ func TestParseConstraint(t *testing.T) {
 			Input: ">=1.2.3-beta.0",
 			ExpectConstr: &Constraint{
 				left: NewGuard(
-					NewVersionRaw([]uint32{1, 2, 3}, "beta.0"),
+					newVersionRaw([]uint32{1, 2, 3}, "beta.0"),
 					GuardGreaterOrEqual,
 				),
 				right: (*Guard)(nil),
@@ -538,7 +552,7 @@ func TestParseConstraint(t *testing.T) {
 			Input: ">=1.2.3",
 			ExpectConstr: &Constraint{
 				left: NewGuard(
-					NewVersionRaw([]uint32{1, 2, 3}, ""),
+					newVersionRaw([]uint32{1, 2, 3}, ""),
 					GuardGreaterOrEqual,
 				),
 				right: (*Guard)(nil),
@@ -549,7 +563,7 @@ func TestParseConstraint(t *testing.T) {
 			Input: ">=1.2",
 			ExpectConstr: &Constraint{
 				left: NewGuard(
-					NewVersionRaw([]uint32{1, 2, 0}, ""),
+					newVersionRaw([]uint32{1, 2, 0}, ""),
 					GuardGreaterOrEqual,
 				),
 				right: (*Guard)(nil),
@@ -560,7 +574,7 @@ func TestParseConstraint(t *testing.T) {
 			Input: ">=1",
 			ExpectConstr: &Constraint{
 				left: NewGuard(
-					NewVersionRaw([]uint32{1, 0, 0}, ""),
+					newVersionRaw([]uint32{1, 0, 0}, ""),
 					GuardGreaterOrEqual,
 				),
 				right: (*Guard)(nil),
@@ -571,7 +585,7 @@ func TestParseConstraint(t *testing.T) {
 			Input: "<1.2.3-beta.0",
 			ExpectConstr: &Constraint{
 				left: NewGuard(
-					NewVersionRaw([]uint32{1, 2, 3}, "beta.0"),
+					newVersionRaw([]uint32{1, 2, 3}, "beta.0"),
 					GuardLessThan,
 				),
 				right: (*Guard)(nil),
@@ -582,7 +596,7 @@ func TestParseConstraint(t *testing.T) {
 			Input: "<1.2.3",
 			ExpectConstr: &Constraint{
 				left: NewGuard(
-					NewVersionRaw([]uint32{1, 2, 3}, ""),
+					newVersionRaw([]uint32{1, 2, 3}, ""),
 					GuardLessThan,
 				),
 				right: (*Guard)(nil),
@@ -593,7 +607,7 @@ func TestParseConstraint(t *testing.T) {
 			Input: "<1.2",
 			ExpectConstr: &Constraint{
 				left: NewGuard(
-					NewVersionRaw([]uint32{1, 2, 0}, ""),
+					newVersionRaw([]uint32{1, 2, 0}, ""),
 					GuardLessThan,
 				),
 				right: (*Guard)(nil),
@@ -604,7 +618,7 @@ func TestParseConstraint(t *testing.T) {
 			Input: "<1",
 			ExpectConstr: &Constraint{
 				left: NewGuard(
-					NewVersionRaw([]uint32{1, 0, 0}, ""),
+					newVersionRaw([]uint32{1, 0, 0}, ""),
 					GuardLessThan,
 				),
 				right: (*Guard)(nil),
@@ -615,7 +629,7 @@ func TestParseConstraint(t *testing.T) {
 			Input: "<=1.2.3-beta.0",
 			ExpectConstr: &Constraint{
 				left: NewGuard(
-					NewVersionRaw([]uint32{1, 2, 3}, "beta.0"),
+					newVersionRaw([]uint32{1, 2, 3}, "beta.0"),
 					GuardLessOrEqual,
 				),
 				right: (*Guard)(nil),
@@ -626,7 +640,7 @@ func TestParseConstraint(t *testing.T) {
 			Input: "<=1.2",
 			ExpectConstr: &Constraint{
 				left: NewGuard(
-					NewVersionRaw([]uint32{1, 2, 0}, ""),
+					newVersionRaw([]uint32{1, 2, 0}, ""),
 					GuardLessOrEqual,
 				),
 				right: (*Guard)(nil),
@@ -637,7 +651,7 @@ func TestParseConstraint(t *testing.T) {
 			Input: "<=1",
 			ExpectConstr: &Constraint{
 				left: NewGuard(
-					NewVersionRaw([]uint32{1, 0, 0}, ""),
+					newVersionRaw([]uint32{1, 0, 0}, ""),
 					GuardLessOrEqual,
 				),
 				right: (*Guard)(nil),
@@ -648,11 +662,11 @@ func TestParseConstraint(t *testing.T) {
 			Input: "~1.2.3-beta.0",
 			ExpectConstr: &Constraint{
 				left: NewGuard(
-					NewVersionRaw([]uint32{1, 2, 3}, "beta.0"),
+					newVersionRaw([]uint32{1, 2, 3}, "beta.0"),
 					GuardGreaterOrEqual,
 				),
 				right: NewGuard(
-					NewVersionRaw([]uint32{1, 3, 0}, ""),
+					newVersionRaw([]uint32{1, 3, 0}, ""),
 					GuardLessThan,
 				),
 				un: ConstraintUnionAnd,
@@ -662,11 +676,11 @@ func TestParseConstraint(t *testing.T) {
 			Input: "~1.2.0",
 			ExpectConstr: &Constraint{
 				left: NewGuard(
-					NewVersionRaw([]uint32{1, 2, 0}, ""),
+					newVersionRaw([]uint32{1, 2, 0}, ""),
 					GuardGreaterOrEqual,
 				),
 				right: NewGuard(
-					NewVersionRaw([]uint32{1, 3, 0}, ""),
+					newVersionRaw([]uint32{1, 3, 0}, ""),
 					GuardLessThan,
 				),
 				un: ConstraintUnionAnd,
@@ -676,11 +690,11 @@ func TestParseConstraint(t *testing.T) {
 			Input: "~>1.2.0",
 			ExpectConstr: &Constraint{
 				left: NewGuard(
-					NewVersionRaw([]uint32{1, 2, 0}, ""),
+					newVersionRaw([]uint32{1, 2, 0}, ""),
 					GuardGreaterOrEqual,
 				),
 				right: NewGuard(
-					NewVersionRaw([]uint32{1, 3, 0}, ""),
+					newVersionRaw([]uint32{1, 3, 0}, ""),
 					GuardLessThan,
 				),
 				un: ConstraintUnionAnd,
@@ -690,11 +704,11 @@ func TestParseConstraint(t *testing.T) {
 			Input: "~1.2",
 			ExpectConstr: &Constraint{
 				left: NewGuard(
-					NewVersionRaw([]uint32{1, 2, 0}, ""),
+					newVersionRaw([]uint32{1, 2, 0}, ""),
 					GuardGreaterOrEqual,
 				),
 				right: NewGuard(
-					NewVersionRaw([]uint32{1, 3, 0}, ""),
+					newVersionRaw([]uint32{1, 3, 0}, ""),
 					GuardLessThan,
 				),
 				un: ConstraintUnionAnd,
@@ -704,11 +718,47 @@ func TestParseConstraint(t *testing.T) {
 			Input: "~1",
 			ExpectConstr: &Constraint{
 				left: NewGuard(
-					NewVersionRaw([]uint32{1, 0, 0}, ""),
+					newVersionRaw([]uint32{1, 0, 0}, ""),
+					GuardGreaterOrEqual,
+				),
+				right: NewGuard(
+					newVersionRaw([]uint32{2, 0, 0}, ""),
+					GuardLessThan,
+				),
+				un: ConstraintUnionAnd,
+			},
+		},
+		{
+			Input: "~0",
+			ExpectConstr: &Constraint{
+				left: NewGuard(
+					newVersionRaw([]uint32{0, 0, 0}, ""),
+					GuardGreaterOrEqual,
+				),
+				right: (*Guard)(nil),
+				un:    ConstraintUnionOr,
+			},
+		},
+		{
+			Input: "~0.0.0",
+			ExpectConstr: &Constraint{
+				left: NewGuard(
+					newVersionRaw([]uint32{0, 0, 0}, ""),
+					GuardGreaterOrEqual,
+				),
+				right: (*Guard)(nil),
+				un:    ConstraintUnionOr,
+			},
+		},
+		{
+			Input: "~0.1",
+			ExpectConstr: &Constraint{
+				left: NewGuard(
+					newVersionRaw([]uint32{0, 1, 0}, ""),
 					GuardGreaterOrEqual,
 				),
 				right: NewGuard(
-					NewVersionRaw([]uint32{2, 0, 0}, ""),
+					newVersionRaw([]uint32{0, 2, 0}, ""),
 					GuardLessThan,
 				),
 				un: ConstraintUnionAnd,
@@ -718,7 +768,7 @@ func TestParseConstraint(t *testing.T) {
 			Input: "~>*",
 			ExpectConstr: &Constraint{
 				left: NewGuard(
-					NewVersionRaw([]uint32{0, 0, 0}, ""),
+					newVersionRaw([]uint32{0, 0, 0}, ""),
 					GuardGreaterOrEqual,
 				),
 				right: (*Guard)(nil),
@@ -729,11 +779,11 @@ func TestParseConstraint(t *testing.T) {
 			Input: "~>2.x.x",
 			ExpectConstr: &Constraint{
 				left: NewGuard(
-					NewVersionRaw([]uint32{2, 0, 0}, ""),
+					newVersionRaw([]uint32{2, 0, 0}, ""),
 					GuardGreaterOrEqual,
 				),
 				right: NewGuard(
-					NewVersionRaw([]uint32{3, 0, 0}, ""),
+					newVersionRaw([]uint32{3, 0, 0}, ""),
 					GuardLessThan,
 				),
 				un: ConstraintUnionAnd,
@@ -743,7 +793,7 @@ func TestParseConstraint(t *testing.T) {
 			Input: "^*",
 			ExpectConstr: &Constraint{
 				left: NewGuard(
-					NewVersionRaw([]uint32{0, 0, 0}, ""),
+					newVersionRaw([]uint32{0, 0, 0}, ""),
 					GuardGreaterOrEqual,
 				),
 				right: (*Guard)(nil),
@@ -754,11 +804,11 @@ func TestParseConstraint(t *testing.T) {
 			Input: "^1.2.3",
 			ExpectConstr: &Constraint{
 				left: NewGuard(
-					NewVersionRaw([]uint32{1, 2, 3}, ""),
+					newVersionRaw([]uint32{1, 2, 3}, ""),
 					GuardGreaterOrEqual,
 				),
 				right: NewGuard(
-					NewVersionRaw([]uint32{2, 0, 0}, ""),
+					newVersionRaw([]uint32{2, 0, 0}, ""),
 					GuardLessThan,
 				),
 				un: ConstraintUnionAnd,
@@ -768,11 +818,11 @@ func TestParseConstraint(t *testing.T) {
 			Input: "^1.2",
 			ExpectConstr: &Constraint{
 				left: NewGuard(
-					NewVersionRaw([]uint32{1, 2, 0}, ""),
+					newVersionRaw([]uint32{1, 2, 0}, ""),
 					GuardGreaterOrEqual,
 				),
 				right: NewGuard(
-					NewVersionRaw([]uint32{2, 0, 0}, ""),
+					newVersionRaw([]uint32{2, 0, 0}, ""),
 					GuardLessThan,
 				),
 				un: ConstraintUnionAnd,
@@ -782,11 +832,11 @@ func TestParseConstraint(t *testing.T) {
 			Input: "^1",
 			ExpectConstr: &Constraint{
 				left: NewGuard(
-					NewVersionRaw([]uint32{1, 0, 0}, ""),
+					newVersionRaw([]uint32{1, 0, 0}, ""),
 					GuardGreaterOrEqual,
 				),
 				right: NewGuard(
-					NewVersionRaw([]uint32{2, 0, 0}, ""),
+					newVersionRaw([]uint32{2, 0, 0}, ""),
 					GuardLessThan,
 				),
 				un: ConstraintUnionAnd,
@@ -796,11 +846,11 @@ func TestParseConstraint(t *testing.T) {
 			Input: "^0.2.3",
 			ExpectConstr: &Constraint{
 				left: NewGuard(
-					NewVersionRaw([]uint32{0, 2, 3}, ""),
+					newVersionRaw([]uint32{0, 2, 3}, ""),
 					GuardGreaterOrEqual,
 				),
 				right: NewGuard(
-					NewVersionRaw([]uint32{0, 3, 0}, ""),
+					newVersionRaw([]uint32{0, 3, 0}, ""),
 					GuardLessThan,
 				),
 				un: ConstraintUnionAnd,
@@ -810,11 +860,11 @@ func TestParseConstraint(t *testing.T) {
 			Input: "^0.2",
 			ExpectConstr: &Constraint{
 				left: NewGuard(
-					NewVersionRaw([]uint32{0, 2, 0}, ""),
+					newVersionRaw([]uint32{0, 2, 0}, ""),
 					GuardGreaterOrEqual,
 				),
 				right: NewGuard(
-					NewVersionRaw([]uint32{0, 3, 0}, ""),
+					newVersionRaw([]uint32{0, 3, 0}, ""),
 					GuardLessThan,
 				),
 				un: ConstraintUnionAnd,
@@ -824,11 +874,11 @@ func TestParseConstraint(t *testing.T) {
 			Input: "^0.0.3",
 			ExpectConstr: &Constraint{
 				left: NewGuard(
-					NewVersionRaw([]uint32{0, 0, 3}, ""),
+					newVersionRaw([]uint32{0, 0, 3}, ""),
 					GuardGreaterOrEqual,
 				),
 				right: NewGuard(
-					NewVersionRaw([]uint32{0, 0, 4}, ""),
+					newVersionRaw([]uint32{0, 0, 4}, ""),
 					GuardLessThan,
 				),
 				un: ConstraintUnionAnd,
@@ -838,11 +888,11 @@ func TestParseConstraint(t *testing.T) {
 			Input: "^0.0",
 			ExpectConstr: &Constraint{
 				left: NewGuard(
-					NewVersionRaw([]uint32{0, 0, 0}, ""),
+					newVersionRaw([]uint32{0, 0, 0}, ""),
 					GuardGreaterOrEqual,
 				),
 				right: NewGuard(
-					NewVersionRaw([]uint32{0, 1, 0}, ""),
+					newVersionRaw([]uint32{0, 1, 0}, ""),
 					GuardLessThan,
 				),
 				un: ConstraintUnionAnd,
@@ -852,21 +902,40 @@ func TestParseConstraint(t *testing.T) {
 			Input: "^0",
 			ExpectConstr: &Constraint{
 				left: NewGuard(
-					NewVersionRaw([]uint32{0, 0, 0}, ""),
+					newVersionRaw([]uint32{0, 0, 0}, ""),
 					GuardGreaterOrEqual,
 				),
 				right: NewGuard(
-					NewVersionRaw([]uint32{1, 0, 0}, ""),
+					newVersionRaw([]uint32{1, 0, 0}, ""),
 					GuardLessThan,
 				),
 				un: ConstraintUnionAnd,
 			},
 		},
+		{
+			Input: ">=\t1.2.3\n",
+			ExpectConstr: &Constraint{
+				left: NewGuard(
+					newVersionRaw([]uint32{1, 2, 3}, ""),
+					GuardGreaterOrEqual,
+				),
+				right: (*Guard)(nil),
+				un:    ConstraintUnionOr,
+			},
+		},
+		{
+			Input:     "1.2.3-\t",
+			ExpectErr: newParseError(ErrInvalidConstraint, "1.2.3-\t", 6, 6, ReasonEmptyIdentifier),
+		},
+		{
+			Input:     "1.2.3-",
+			ExpectErr: newParseError(ErrInvalidConstraint, "1.2.3-", 6, 6, ReasonEmptyIdentifier),
+		},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.Input, func(t *testing.T) {
-			c, err := parseConstraint(tt.Input)
+			c, err := parseConstraint(tt.Input, dialects[DialectDefault])
 			if !errorEqual(err, tt.ExpectErr) {
 				t.Fatalf("unexpected error: got: %q, want: %q", err, tt.ExpectErr)
 			}
@@ -880,6 +949,822 @@ func TestParseConstraint(t *testing.T) {
 	}
 }
 
+func TestConstraintPrereleasePolicy(t *testing.T) {
+	tests := []struct {
+		Constraint string
+		Version    string
+		Policy     PrereleasePolicy
+		Expect     bool
+	}{
+		{Constraint: "^1.2.0", Version: "1.5.0", Policy: PrereleaseSameTuple, Expect: true},
+		{Constraint: "^1.2.0", Version: "1.5.0-alpha", Policy: PrereleaseSameTuple, Expect: false},
+		{Constraint: "^1.2.0", Version: "1.5.0-alpha", Policy: PrereleaseExclude, Expect: false},
+		{Constraint: "^1.2.0", Version: "1.5.0-alpha", Policy: PrereleaseInclude, Expect: true},
+		{Constraint: ">=1.5.0-alpha, <2.0.0", Version: "1.5.0-beta", Policy: PrereleaseSameTuple, Expect: true},
+		{Constraint: ">=1.5.0-alpha, <2.0.0", Version: "1.5.1-beta", Policy: PrereleaseSameTuple, Expect: false},
+		{Constraint: ">=1.5.0-alpha, <2.0.0", Version: "1.5.0-beta", Policy: PrereleaseExclude, Expect: false},
+		{Constraint: ">=1.5.0-alpha, <2.0.0", Version: "1.6.0", Policy: PrereleaseExclude, Expect: true},
+		{Constraint: "^1.2.3-beta.2", Version: "1.2.3-beta.4", Policy: PrereleaseSameTuple, Expect: true},
+		{Constraint: "^1.2.3-beta.2", Version: "1.2.3-alpha.9", Policy: PrereleaseSameTuple, Expect: false},
+		{Constraint: "1.0.0 || ^1.2.3-beta.2", Version: "1.2.3-beta.4", Policy: PrereleaseSameTuple, Expect: true},
+		{Constraint: "<2.0.0", Version: "2.0.0-rc.1", Policy: PrereleaseSameTuple, Expect: false},
+		{Constraint: "<2.0.0", Version: "2.0.0-rc.1", Policy: PrereleaseInclude, Expect: true},
+		// The tuple is looked for in the whole constraint, npm rejects these
+		{Constraint: "<1.0.0 || >=1.0.0-rc.1 <1.0.0-rc.3", Version: "1.0.0-rc.5", Policy: PrereleaseSameTuple, Expect: true},
+		{Constraint: "<1.0.0 || 2.0.0-rc.1 || 1.0.0-rc.1", Version: "1.0.0-beta", Policy: PrereleaseSameTuple, Expect: true},
+		{Constraint: "<1.0.0 || 2.0.0-rc.1", Version: "1.0.0-beta", Policy: PrereleaseSameTuple, Expect: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.Constraint+"/"+tt.Version, func(t *testing.T) {
+			c, err := NewConstraint(tt.Constraint, WithPrereleasePolicy(tt.Policy))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if c.PrereleasePolicy() != tt.Policy {
+				t.Fatalf("unexpected pre-release policy: got: %d, want: %d", c.PrereleasePolicy(), tt.Policy)
+			}
+			if check := c.Check(newVersionUnsafe(tt.Version)); check != tt.Expect {
+				t.Fatalf("unexpected check result for %q: got: %t, want: %t", tt.Version, check, tt.Expect)
+			}
+		})
+	}
+}
+
+func TestHyphenRange(t *testing.T) {
+	tests := []struct {
+		Input     string
+		Expect    string
+		ExpectErr ParseErrorReason
+	}{
+		{Input: "1.2.3 - 2.3.4", Expect: ">=1.2.3, <=2.3.4"},
+		{Input: "1.2 - 2", Expect: ">=1.2.0, <3.0.0"},
+		{Input: "1.2.3 - 2.3", Expect: ">=1.2.3, <2.4.0"},
+		{Input: "1.2.x - 2.3.x", Expect: ">=1.2.0, <2.4.0"},
+		{Input: "* - 2.0.0", Expect: ">=0.0.0, <=2.0.0"},
+		{Input: "1.2.3 - *", Expect: ">=1.2.3"},
+		{Input: "v1.2.3-beta.1 - v2.0.0-rc.1", Expect: ">=1.2.3-beta.1, <=2.0.0-rc.1"},
+		{Input: "1.2.3 - 2.3.4 || 5.x", Expect: ">=1.2.3, <=2.3.4 || >=5.0.0, <6.0.0"},
+		{Input: "1.0.0 - 2.0.0, !=1.5.0", Expect: ">=1.0.0, <1.5.0 || >1.5.0, <=2.0.0"},
+		{Input: ">=1.2.3 - 2", ExpectErr: ReasonUnexpectedChar},
+		{Input: "1.2.3 - <2", ExpectErr: ReasonUnexpectedChar},
+		{Input: "1.2.3 - 2 - 3", ExpectErr: ReasonUnexpectedChar},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.Input, func(t *testing.T) {
+			c, err := NewConstraint(tt.Input)
+			if tt.Expect == "" {
+				var perr *ParseError
+				if !errors.As(err, &perr) || perr.Reason != tt.ExpectErr {
+					t.Fatalf("unexpected error: got: %v, want: %s", err, tt.ExpectErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if s := c.String(); s != tt.Expect {
+				t.Fatalf("unexpected constraint string: got: %q, want: %q", s, tt.Expect)
+			}
+		})
+	}
+
+	c, _ := NewConstraint("1.2 - 2")
+	for v, expect := range map[string]bool{"1.1.9": false, "1.2.0": true, "2.9.9": true, "3.0.0": false} {
+		if check := c.Check(newVersionUnsafe(v)); check != expect {
+			t.Fatalf("unexpected check result for %q: got: %t, want: %t", v, check, expect)
+		}
+	}
+}
+
+func TestWhitespaceAnd(t *testing.T) {
+	tests := []struct {
+		Input     string
+		Expect    string
+		ExpectErr ParseError
+	}{
+		{Input: ">=1.2.0 <2.0.0", Expect: ">=1.2.0, <2.0.0"},
+		{Input: ">= 1.2.0 < 2.0.0, !=1.5.0", Expect: ">=1.2.0, <1.5.0 || >1.5.0, <2.0.0"},
+		{Input: "  >=1.2.0   <2.0.0  ||  ^3  ", Expect: ">=1.2.0, <2.0.0 || >=3.0.0, <4.0.0"},
+		{Input: "1.2.3 - 2.3.4 !=2.0.0", Expect: ">=1.2.3, <2.0.0 || >2.0.0, <=2.3.4"},
+		{Input: "1.2.3  -  2.3.4 || 1.2 - 2 >1.5", Expect: ">=1.2.3, <3.0.0"},
+		{Input: "= v1.2.3-beta.0", Expect: "=1.2.3-beta.0"},
+		{Input: "~1.2,>1.2.1 <1.2.5", Expect: ">1.2.1, <1.2.5"},
+		{Input: "", Expect: ">=0.0.0"},
+		{Input: ">=1.2.3, <2.0.0 || >=3.0.0", Expect: ">=1.2.3, <2.0.0 || >=3.0.0"},
+		{Input: "1.2.3   ||   =>1.5", Expect: "=1.2.3 || >=1.5.0"},
+		{Input: ">=1.2.3\t<2.0.0\r\n", Expect: ">=1.2.3, <2.0.0"},
+		{Input: "1.2.3\t||\t^2", Expect: "=1.2.3 || >=2.0.0, <3.0.0"},
+		{
+			Input:     "1.2.3- 2.0.0",
+			ExpectErr: ParseError{Offset: 6, Token: "", Reason: ReasonEmptyIdentifier},
+		},
+		{
+			Input:     ">=1.2.3, <2.0.0 || >=3.0.0, ~4.x.y",
+			ExpectErr: ParseError{Offset: 33, Token: "y", Reason: ReasonUnexpectedChar},
+		},
+		{
+			Input:     ">=1.2.0 <2.0.0 >=y",
+			ExpectErr: ParseError{Offset: 17, Token: "y", Reason: ReasonUnexpectedChar},
+		},
+		{
+			Input:     "^1 || >=1.2.0 => <2",
+			ExpectErr: ParseError{Offset: 17, Token: "<", Reason: ReasonUnexpectedChar},
+		},
+		{
+			Input:     ">=1.0.0, 1.2.3 -",
+			ExpectErr: ParseError{Offset: 15, Token: "-", Reason: ReasonUnexpectedChar},
+		},
+		{
+			Input:     "1.0.0 - 1.2.3 - 2.0.0",
+			ExpectErr: ParseError{Offset: 14, Token: "-", Reason: ReasonUnexpectedChar},
+		},
+		{
+			Input:     "1.2.3 - ",
+			ExpectErr: ParseError{Offset: 6, Token: "-", Reason: ReasonUnexpectedChar},
+		},
+		{
+			Input:     "1.2.3 -   || 2.0.0",
+			ExpectErr: ParseError{Offset: 6, Token: "-", Reason: ReasonUnexpectedChar},
+		},
+		{
+			Input:     ">=1.0.0 <  ",
+			ExpectErr: ParseError{Offset: 8, Token: "<", Reason: ReasonMissingComponent},
+		},
+		{
+			Input:     ">= , <2.0.0",
+			ExpectErr: ParseError{Offset: 0, Token: ">=", Reason: ReasonMissingComponent},
+		},
+		{
+			Input:     "^",
+			ExpectErr: ParseError{Offset: 0, Token: "^", Reason: ReasonMissingComponent},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.Input, func(t *testing.T) {
+			c, err := NewConstraint(tt.Input)
+			if tt.Expect == "" {
+				var perr *ParseError
+				if !errors.As(err, &perr) {
+					t.Fatalf("unexpected error: got: %v, want: %T", err, perr)
+				}
+				tt.ExpectErr.Input, tt.ExpectErr.Err = tt.Input, ErrInvalidConstraint
+				if !reflect.DeepEqual(*perr, tt.ExpectErr) {
+					t.Fatalf("unexpected parse error: got: %+v, want: %+v", *perr, tt.ExpectErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if s := c.String(); s != tt.Expect {
+				t.Fatalf("unexpected constraint string: got: %q, want: %q", s, tt.Expect)
+			}
+		})
+	}
+}
+
+func TestConstraintString(t *testing.T) {
+	tests := []struct {
+		Input  string
+		Expect string
+	}{
+		{Input: "=1.2.3", Expect: "=1.2.3"},
+		{Input: "=v1.2", Expect: ">=1.2.0, <1.3.0"},
+		{Input: "1.2.x", Expect: ">=1.2.0, <1.3.0"},
+		{Input: "!=1.2.3", Expect: "<1.2.3 || >1.2.3"},
+		{Input: ">1.2.3-beta.0", Expect: ">1.2.3-beta.0"},
+		{Input: ">1.2", Expect: ">=1.3.0"},
+		{Input: "<=1.2.3", Expect: "<=1.2.3"},
+		{Input: "~1.2.3", Expect: ">=1.2.3, <1.3.0"},
+		{Input: "~*", Expect: ">=0.0.0"},
+		{Input: "*", Expect: ">=0.0.0"},
+		{Input: "^0.2", Expect: ">=0.2.0, <0.3.0"},
+		{Input: "^0.0.3", Expect: ">=0.0.3, <0.0.4"},
+		{Input: "^1.2.3+build.5", Expect: ">=1.2.3, <2.0.0"},
+		{Input: "^0.2 || 1.x", Expect: ">=0.2.0, <0.3.0 || >=1.0.0, <2.0.0"},
+		{Input: ">=1.2.3, <2.0.0 || >=3.0.0", Expect: ">=1.2.3, <2.0.0 || >=3.0.0"},
+		{Input: "!=1.2, >=1.0.0", Expect: ">=1.0.0, <1.2.0 || >=1.3.0"},
+		{Input: ">=1.0.0, !=1.3.1, !=1.4.2", Expect: ">=1.0.0, <1.3.1 || >1.3.1, <1.4.2 || >1.4.2"},
+		{Input: "^1.0 || 1.2.3-rc.1", Expect: ">=1.0.0, <1.2.3-0 || >=1.2.3-0, <2.0.0"},
+		{Input: ">2.0.0, <1.0.0", Expect: "<0.0.0-0"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.Input, func(t *testing.T) {
+			c, err := NewConstraint(tt.Input)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if s := c.String(); s != tt.Expect {
+				t.Fatalf("unexpected constraint string: got: %q, want: %q", s, tt.Expect)
+			}
+			if s := c.Original(); s != tt.Input {
+				t.Fatalf("unexpected original constraint: got: %q, want: %q", s, tt.Input)
+			}
+			rc, err := NewConstraint(c.String())
+			if err != nil {
+				t.Fatalf("unexpected error parsing %q: %v", c.String(), err)
+			}
+			if s := rc.String(); s != tt.Expect {
+				t.Fatalf("unexpected round trip constraint string: got: %q, want: %q", s, tt.Expect)
+			}
+		})
+	}
+
+	// The rendering grows with the number of ranges, not with the tree
+	var b strings.Builder
+	b.WriteString(">=1.0.0")
+	for i := 0; i < 18; i++ {
+		fmt.Fprintf(&b, ", !=1.%d.0", i)
+	}
+	c, err := NewConstraint(b.String())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if s := c.String(); len(s) > 20*40 || !strings.HasSuffix(s, " || >1.17.0") {
+		t.Fatalf("unexpected constraint string of %d bytes: %.80q", len(s), s)
+	}
+}
+
+func TestNewConstraintError(t *testing.T) {
+	tests := []struct {
+		Input  string
+		Expect ParseError
+	}{
+		{
+			Input:  "<>1.2.3",
+			Expect: ParseError{Offset: 0, Token: "<>", Reason: ReasonUnknownOperator},
+		},
+		{
+			Input:  ">=1.2.3, <2.0.0|| >=3.0.0, ~4.x.y",
+			Expect: ParseError{Offset: 32, Token: "y", Reason: ReasonUnexpectedChar},
+		},
+		{
+			Input:  "^1.2.3.4",
+			Expect: ParseError{Offset: 7, Token: "4", Reason: ReasonTooManyComponents},
+		},
+		{
+			Input:  "1.2.3|| =>1.99999999999999999999",
+			Expect: ParseError{Offset: 12, Token: "99999999999999999999", Reason: ReasonOverflow},
+		},
+		{
+			Input:  "~1.1024.0, <2097152",
+			Expect: ParseError{Offset: 12, Token: "2097152", Reason: ReasonOverflow},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.Input, func(t *testing.T) {
+			_, err := NewConstraint(tt.Input)
+			if !errors.Is(err, ErrInvalidConstraint) {
+				t.Fatalf("unexpected error: got: %v, want: %v", err, ErrInvalidConstraint)
+			}
+			var perr *ParseError
+			if !errors.As(err, &perr) {
+				t.Fatalf("unexpected error type: got: %T, want: %T", err, perr)
+			}
+			tt.Expect.Input, tt.Expect.Err = tt.Input, ErrInvalidConstraint
+			if !reflect.DeepEqual(*perr, tt.Expect) {
+				t.Fatalf("unexpected parse error: got: %+v, want: %+v", *perr, tt.Expect)
+			}
+		})
+	}
+}
+
+func TestIntersect(t *testing.T) {
+	tests := []struct {
+		A, B     string
+		Expect   string
+		ExpectOk bool
+	}{
+		{A: "^1.2.0", B: "~1.4", Expect: ">=1.4.0, <1.5.0", ExpectOk: true},
+		{A: ">=1.0.0", B: "<1.0.0", Expect: "<0.0.0-0", ExpectOk: false},
+		{A: "1.x || 3.x", B: ">=1.5.0, <3.2.0", Expect: ">=1.5.0, <2.0.0 || >=3.0.0, <3.2.0", ExpectOk: true},
+		{A: ">1.2.3", B: "<=1.2.3", Expect: "<0.0.0-0", ExpectOk: false},
+		{A: ">1.2.3", B: "<=1.2.4", Expect: ">1.2.3, <=1.2.4", ExpectOk: true},
+		{A: ">=1.2.3", B: "<=1.2.3", Expect: "=1.2.3", ExpectOk: true},
+		{A: "!=1.2.3", B: "1.2.x", Expect: ">=1.2.0, <1.2.3 || >1.2.3, <1.3.0", ExpectOk: true},
+		{A: "<1.0.0", B: "<2.0.0", Expect: "<1.0.0", ExpectOk: true},
+		{A: "*", B: "*", Expect: ">=0.0.0", ExpectOk: true},
+		{A: ">=1.0.0-rc.1", B: "<1.0.0", Expect: ">=1.0.0-rc.1, <1.0.0", ExpectOk: true},
+		{A: "1.2.3-rc.1 || 1.2.3-rc.2", B: ">1.2.3-rc.1", Expect: "=1.2.3-rc.2", ExpectOk: true},
+		{A: ">=1.2.3", B: "<1.3.0-0", Expect: ">=1.2.3, <1.3.0-0", ExpectOk: true},
+		{A: ">1.2.3-0", B: "<=1.2.3", Expect: ">1.2.3-0, <=1.2.3", ExpectOk: true},
+	}
+
+	r := rand.New(rand.NewSource(3))
+	for _, tt := range tests {
+		t.Run(tt.A+" & "+tt.B, func(t *testing.T) {
+			a, _ := NewConstraint(tt.A, WithPrereleasePolicy(PrereleaseInclude))
+			b, _ := NewConstraint(tt.B, WithPrereleasePolicy(PrereleaseInclude))
+			c, ok := Intersect(a, b)
+			if ok != tt.ExpectOk {
+				t.Fatalf("unexpected intersection result: got: %t, want: %t", ok, tt.ExpectOk)
+			}
+			if s := c.String(); s != tt.Expect {
+				t.Fatalf("unexpected constraint string: got: %q, want: %q", s, tt.Expect)
+			}
+			for n := 0; n < 1000; n++ {
+				v := newVersionUnsafe(randVersion(r))
+				if got, want := c.Check(v), a.Check(v) && b.Check(v); got != want {
+					t.Fatalf("unexpected check of %s: got: %t, want: %t", v, got, want)
+				}
+			}
+		})
+	}
+}
+
+// policyConstraints mention pre-releases of the versions randVersion returns.
+var policyConstraints = []string{
+	"^1.1.0",
+	">=1.1.0-alpha",
+	">=1.1.0-alpha, <1.1.0",
+	"<1.0.0 || >=1.0.0-rc.1 <1.0.0-rc.3",
+	"=1.0.0-rc.1",
+	">=0.5.0, <2.0.0",
+	"!=1.1.1-beta",
+	"~1.2.1-alpha.1 || 2.x",
+	">1.1.1, <1.1.2",
+	"*",
+	"<0.0.0-0",
+	"1.2.2-beta || ^0.1.1-alpha",
+	">=1.0.0-0",
+	"<2.0.0-0",
+}
+
+var policies = []PrereleasePolicy{PrereleaseSameTuple, PrereleaseExclude, PrereleaseInclude}
+
+func TestIntersectPolicies(t *testing.T) {
+	a, _ := NewConstraint("^1.2.0")
+	b, _ := NewConstraint(">=1.5.0-alpha")
+	if c, _ := Intersect(a, b); c.Check(newVersionUnsafe("1.5.0-beta")) {
+		t.Fatalf("unexpected check of 1.5.0-beta by %q", c)
+	}
+	b, _ = NewConstraint(">=1.5.0-alpha, <1.5.0")
+	if c, ok := Intersect(a, b); ok || !c.IsEmpty() {
+		t.Fatalf("unexpected intersection %q: got: %t, want: %t", c, ok, false)
+	}
+
+	r := rand.New(rand.NewSource(12))
+	for _, sa := range policyConstraints {
+		for _, sb := range policyConstraints {
+			for _, pa := range policies {
+				for _, pb := range policies {
+					a, _ := NewConstraint(sa, WithPrereleasePolicy(pa))
+					b, _ := NewConstraint(sb, WithPrereleasePolicy(pb))
+					c, ok := Intersect(a, b)
+					rc, err := NewConstraint(c.String(), WithPrereleasePolicy(c.PrereleasePolicy()))
+					if err != nil {
+						t.Fatalf("unexpected error parsing %q: %v", c, err)
+					}
+					for n := 0; n < 300; n++ {
+						v := newVersionUnsafe(randVersion(r))
+						want := a.Check(v) && b.Check(v)
+						if got := c.Check(v); got != want {
+							t.Fatalf("unexpected check of %s by %q (%d) & %q (%d): got: %t, want: %t", v, sa, pa, sb, pb, got, want)
+						}
+						if got := rc.Check(v); got != want {
+							t.Fatalf("unexpected check of %s by %q: got: %t, want: %t", v, rc, got, want)
+						}
+						if want && !ok {
+							t.Fatalf("unexpected empty intersection of %q (%d) & %q (%d): matches %s", sa, pa, sb, pb, v)
+						}
+					}
+				}
+			}
+		}
+	}
+}
+
+func TestUnion(t *testing.T) {
+	tests := []struct {
+		A, B   string
+		Expect string
+	}{
+		{A: "^1.2.0", B: "~1.4", Expect: ">=1.2.0, <2.0.0"},
+		{A: "1.x", B: "2.x", Expect: ">=1.0.0, <3.0.0"},
+		{A: "1.x", B: "3.x", Expect: ">=1.0.0, <2.0.0 || >=3.0.0, <4.0.0"},
+		{A: "<=1.2.3", B: ">1.2.3", Expect: ">=0.0.0-0"},
+		{A: "<1.2.3", B: ">1.2.3", Expect: "<1.2.3 || >1.2.3"},
+		{A: "1.2.3", B: "1.2.4", Expect: "=1.2.3 || =1.2.4"},
+		{A: "<0.0.0-0", B: "1.2.3", Expect: "=1.2.3"},
+	}
+
+	r := rand.New(rand.NewSource(4))
+	for _, tt := range tests {
+		t.Run(tt.A+" | "+tt.B, func(t *testing.T) {
+			a, _ := NewConstraint(tt.A, WithPrereleasePolicy(PrereleaseInclude))
+			b, _ := NewConstraint(tt.B, WithPrereleasePolicy(PrereleaseInclude))
+			c := Union(a, b)
+			if s := c.String(); s != tt.Expect {
+				t.Fatalf("unexpected constraint string: got: %q, want: %q", s, tt.Expect)
+			}
+			for n := 0; n < 1000; n++ {
+				v := newVersionUnsafe(randVersion(r))
+				if got, want := c.Check(v), a.Check(v) || b.Check(v); got != want {
+					t.Fatalf("unexpected check of %s: got: %t, want: %t", v, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestUnionNotPolicies(t *testing.T) {
+	a, _ := NewConstraint("=1.0.0-rc.1")
+	b, _ := NewConstraint(">=0.5.0, <2.0.0")
+	if c := Union(a, b); !c.Check(newVersionUnsafe("1.0.0-rc.1")) {
+		t.Fatalf("unexpected check of 1.0.0-rc.1 by %q", c)
+	}
+
+	r := rand.New(rand.NewSource(13))
+	for _, sa := range policyConstraints {
+		for _, pa := range policies {
+			a, _ := NewConstraint(sa, WithPrereleasePolicy(pa))
+			nc := Not(a)
+			for n := 0; n < 300; n++ {
+				v := newVersionUnsafe(randVersion(r))
+				allowed := len(v.pre) == 0 || pa == PrereleaseInclude || (pa == PrereleaseSameTuple && a.hasPreTuple(v.base))
+				if got, want := nc.Check(v), allowed && !a.Check(v); got != want {
+					t.Fatalf("unexpected check of %s by Not(%q) (%d): got: %t, want: %t", v, sa, pa, got, want)
+				}
+			}
+			for _, sb := range policyConstraints {
+				for _, pb := range policies {
+					b, _ := NewConstraint(sb, WithPrereleasePolicy(pb))
+					c := Union(a, b)
+					rc, err := NewConstraint(c.String(), WithPrereleasePolicy(c.PrereleasePolicy()))
+					if err != nil {
+						t.Fatalf("unexpected error parsing %q: %v", c, err)
+					}
+					for n := 0; n < 300; n++ {
+						v := newVersionUnsafe(randVersion(r))
+						want := a.Check(v) || b.Check(v)
+						got := c.Check(v)
+						// Include combines with another policy into a superset only
+						if got != want && (want || len(v.pre) == 0 || (pa != PrereleaseInclude) == (pb != PrereleaseInclude)) {
+							t.Fatalf("unexpected check of %s by %q (%d) | %q (%d): got: %t, want: %t", v, sa, pa, sb, pb, got, want)
+						}
+						if rc.Check(v) != got {
+							t.Fatalf("unexpected check of %s by %q: got: %t, want: %t", v, rc, !got, got)
+						}
+					}
+				}
+			}
+		}
+	}
+}
+
+func TestNot(t *testing.T) {
+	tests := []struct {
+		Input  string
+		Expect string
+	}{
+		{Input: "^1.2", Expect: "<1.2.0 || >=2.0.0"},
+		{Input: "1.2.3", Expect: "<1.2.3 || >1.2.3"},
+		{Input: "!=1.2.3", Expect: "=1.2.3"},
+		{Input: ">1.2.3", Expect: "<=1.2.3"},
+		{Input: ">=1.2.3-rc.1, <=2.0.0", Expect: "<1.2.3-rc.1 || >2.0.0"},
+		{Input: "<0.0.0", Expect: ">=0.0.0"},
+		{Input: "<0.0.0-0", Expect: ">=0.0.0-0"},
+		{Input: "*", Expect: "<0.0.0"},
+		{Input: ">=1.0.0-0", Expect: "<1.0.0-0"},
+		{Input: "<1.0.0-0", Expect: ">=1.0.0-0"},
+		{Input: ">=0.1.0-0", Expect: "<0.1.0-0"},
+		{Input: "<0.0.1-0", Expect: ">0.0.0"},
+	}
+
+	r := rand.New(rand.NewSource(5))
+	for _, tt := range tests {
+		t.Run(tt.Input, func(t *testing.T) {
+			c, _ := NewConstraint(tt.Input, WithPrereleasePolicy(PrereleaseInclude))
+			nc := Not(c)
+			if s := nc.String(); s != tt.Expect {
+				t.Fatalf("unexpected constraint string: got: %q, want: %q", s, tt.Expect)
+			}
+			if s, norm := Not(nc).String(), Union(c, c).String(); s != norm {
+				t.Fatalf("unexpected double complement: got: %q, want: %q", s, norm)
+			}
+			for n := 0; n < 1000; n++ {
+				v := newVersionUnsafe(randVersion(r))
+				if got, want := nc.Check(v), !c.Check(v); got != want {
+					t.Fatalf("unexpected check of %s: got: %t, want: %t", v, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestIntervals(t *testing.T) {
+	tests := []struct {
+		Input  string
+		Expect []string
+	}{
+		{Input: "~1.2", Expect: []string{"[1.2.0, 1.3.0)"}},
+		{Input: ">=1.2.0, <1.3.0", Expect: []string{"[1.2.0, 1.3.0)"}},
+		{Input: ">1.2.3", Expect: []string{"[1.2.4-0, )"}},
+		{Input: "<=1.2.3-rc.1", Expect: []string{"[0.0.0-0, 1.2.3-rc.1.0)"}},
+		{Input: "1.x || 2.x || 4.x", Expect: []string{"[1.0.0, 3.0.0)", "[4.0.0, 5.0.0)"}},
+		{Input: "!=1.2.3", Expect: []string{"[0.0.0-0, 1.2.3)", "[1.2.4-0, )"}},
+		{Input: ">=2.0.0, <1.0.0", Expect: []string{}},
+	}
+
+	r := rand.New(rand.NewSource(6))
+	for _, tt := range tests {
+		t.Run(tt.Input, func(t *testing.T) {
+			c, err := NewConstraint(tt.Input, WithPrereleasePolicy(PrereleaseInclude))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			ivs := make([]string, 0, len(tt.Expect))
+			for _, iv := range c.Intervals() {
+				hi := ""
+				if iv.Hi != nil {
+					hi = iv.Hi.String()
+				}
+				ivs = append(ivs, "["+iv.Lo.String()+", "+hi+")")
+			}
+			if !reflect.DeepEqual(ivs, tt.Expect) {
+				t.Fatalf("unexpected intervals: got: %q, want: %q", ivs, tt.Expect)
+			}
+			for n := 0; n < 1000; n++ {
+				v := newVersionUnsafe(randVersion(r))
+				if got, want := c.Check(v), c.check(v); got != want {
+					t.Fatalf("unexpected check of %s: got: %t, want: %t", v, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestConstraintRelations(t *testing.T) {
+	tests := []struct {
+		A, B             string
+		Subset, Superset bool
+		Equivalent       bool
+	}{
+		{A: "~1.2.3", B: "^1.2", Subset: true},
+		{A: "~1.2", B: ">=1.2.0, <1.3.0", Subset: true, Superset: true, Equivalent: true},
+		{A: "1.x || 2.x", B: ">=1.0.0, <3.0.0", Subset: true, Superset: true, Equivalent: true},
+		{A: "^1.2", B: "^1.3", Superset: true},
+		{A: ">1.2.3", B: ">=1.2.3", Subset: true},
+		{A: "1.2.3", B: "!=1.2.3"},
+		{A: ">2.0.0, <1.0.0", B: "1.2.3", Subset: true},
+		{A: "*", B: ">=0.0.0", Subset: true, Superset: true, Equivalent: true},
+		{A: "<0.0.0", B: "*"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.A+" ~ "+tt.B, func(t *testing.T) {
+			a, _ := NewConstraint(tt.A)
+			b, _ := NewConstraint(tt.B)
+			if got := a.IsSubsetOf(b); got != tt.Subset {
+				t.Fatalf("unexpected subset check: got: %t, want: %t", got, tt.Subset)
+			}
+			if got := b.IsSubsetOf(a); got != tt.Superset {
+				t.Fatalf("unexpected superset check: got: %t, want: %t", got, tt.Superset)
+			}
+			if got := a.Equivalent(b); got != tt.Equivalent {
+				t.Fatalf("unexpected equivalence check: got: %t, want: %t", got, tt.Equivalent)
+			}
+		})
+	}
+}
+
+func TestConstraintEmptyAny(t *testing.T) {
+	tests := []struct {
+		Input string
+		Empty bool
+		Any   bool
+	}{
+		{Input: "*", Any: true},
+		{Input: "", Any: true},
+		{Input: "<1.0.0 || >=1.0.0", Any: true},
+		{Input: ">=0.0.0-alpha", Any: true},
+		{Input: ">0.0.0"},
+		{Input: "^1.2"},
+		{Input: ">2.0.0, <1.0.0", Empty: true},
+		{Input: ">1.2.3, <=1.2.3", Empty: true},
+		{Input: "<0.0.0-0", Empty: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.Input, func(t *testing.T) {
+			c, err := NewConstraint(tt.Input)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got := c.IsEmpty(); got != tt.Empty {
+				t.Fatalf("unexpected emptiness check: got: %t, want: %t", got, tt.Empty)
+			}
+			if got := c.IsAny(); got != tt.Any {
+				t.Fatalf("unexpected any check: got: %t, want: %t", got, tt.Any)
+			}
+			text, _ := c.MarshalText()
+			for _, s := range []string{c.String(), c.Simplify().String(), c.Sugared(), string(text)} {
+				rc, err := NewConstraint(s)
+				if err != nil {
+					t.Fatalf("unexpected error parsing %q: %v", s, err)
+				}
+				if rc.IsEmpty() != tt.Empty || rc.IsAny() != tt.Any {
+					t.Fatalf("unexpected constraint %q: not equivalent to %q", s, tt.Input)
+				}
+			}
+		})
+	}
+}
+
+func TestSimplify(t *testing.T) {
+	tests := []struct {
+		Input         string
+		Expect        string
+		ExpectSugared string
+	}{
+		{Input: ">=1.0.0, >=1.2.0, <3.0.0, <2.5.0 || ^1.4", Expect: ">=1.2.0, <2.5.0", ExpectSugared: ">=1.2.0, <2.5.0"},
+		{Input: ">=1.2.0, <2.0.0 || >=3.1.0, <3.2.0", Expect: ">=1.2.0, <2.0.0 || >=3.1.0, <3.2.0", ExpectSugared: "^1.2.0 || ~3.1.0"},
+		{Input: "1.x || 2.x", Expect: ">=1.0.0, <3.0.0", ExpectSugared: ">=1.0.0, <3.0.0"},
+		{Input: "^0.2.3", Expect: ">=0.2.3, <0.3.0", ExpectSugared: "^0.2.3"},
+		{Input: "^0.0.3", Expect: ">=0.0.3, <0.0.4", ExpectSugared: "^0.0.3"},
+		{Input: ">=0.0.3, <=0.0.3", Expect: "=0.0.3", ExpectSugared: "=0.0.3"},
+		{Input: "~1.2.3-rc.1", Expect: ">=1.2.3-rc.1, <1.3.0", ExpectSugared: "~1.2.3-rc.1"},
+		{Input: ">1.2.3, <2.0.0", Expect: ">1.2.3, <2.0.0", ExpectSugared: ">1.2.3, <2.0.0"},
+		{Input: "!=1.2.3, !=1.2.3", Expect: "<1.2.3 || >1.2.3", ExpectSugared: "<1.2.3 || >1.2.3"},
+		{Input: "* || 1.2.3", Expect: ">=0.0.0", ExpectSugared: "*"},
+		{Input: ">2.0.0, <1.0.0", Expect: "<0.0.0-0", ExpectSugared: "<0.0.0-0"},
+		{Input: ">=1.2.3, <1.3.0-0", Expect: ">=1.2.3, <1.3.0-0", ExpectSugared: ">=1.2.3, <1.3.0-0"},
+		{Input: "^0.0", Expect: ">=0.0.0, <0.1.0", ExpectSugared: ">=0.0.0, <0.1.0"},
+		{Input: ">=0.0.0, <0.1.0", Expect: ">=0.0.0, <0.1.0", ExpectSugared: ">=0.0.0, <0.1.0"},
+		{Input: ">=1.3.0-0", Expect: ">=1.3.0-0", ExpectSugared: ">=1.3.0-0"},
+		{Input: ">1.2.2097151", Expect: ">=1.3.0", ExpectSugared: ">=1.3.0"},
+		{Input: "^1.0 || 1.2.3-rc.1", Expect: ">=1.0.0, <1.2.3-0 || >=1.2.3-0, <2.0.0", ExpectSugared: ">=1.0.0, <1.2.3-0 || >=1.2.3-0, <2.0.0"},
+		{Input: ">1.2.2, <2.0.0 || >=1.2.3-rc.1, <1.2.3-rc.5", Expect: ">=1.2.3-0, <2.0.0", ExpectSugared: ">=1.2.3-0, <2.0.0"},
+		{Input: "~1.2.3-rc.1 || ^1.2.4-beta", Expect: ">=1.2.3-rc.1, <1.2.4-0 || >=1.2.4-0, <2.0.0", ExpectSugared: ">=1.2.3-rc.1, <1.2.4-0 || >=1.2.4-0, <2.0.0"},
+		{Input: ">1.2.2, <1.2.3 || ^2", Expect: ">=2.0.0, <3.0.0", ExpectSugared: "^2.0.0"},
+	}
+
+	r := rand.New(rand.NewSource(9))
+
+	for _, tt := range tests {
+		t.Run(tt.Input, func(t *testing.T) {
+			c, err := NewConstraint(tt.Input)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			sc := c.Simplify()
+			if s := sc.String(); s != tt.Expect {
+				t.Fatalf("unexpected constraint string: got: %q, want: %q", s, tt.Expect)
+			}
+			if s := c.Sugared(); s != tt.ExpectSugared {
+				t.Fatalf("unexpected sugared string: got: %q, want: %q", s, tt.ExpectSugared)
+			}
+			for _, s := range []string{sc.String(), c.Sugared()} {
+				rc, err := NewConstraint(s)
+				if err != nil {
+					t.Fatalf("unexpected error parsing %q: %v", s, err)
+				}
+				for n := 0; n < 1000; n++ {
+					v := newVersionUnsafe(randVersion(r))
+					if got, want := rc.Check(v), c.Check(v); got != want {
+						t.Fatalf("unexpected check of %s by %q: got: %t, want: %t", v, s, got, want)
+					}
+				}
+			}
+			for _, v := range []string{"1.2.3-rc.2", "1.2.4-alpha", "1.3.0-alpha", "0.0.0-0", "0.0.5", "0.1.0-0"} {
+				v := newVersionUnsafe(v)
+				if got, want := sc.Check(v), c.Check(v); got != want {
+					t.Fatalf("unexpected check of %s: got: %t, want: %t", v, got, want)
+				}
+			}
+		})
+	}
+
+	// No equivalent constraint has fewer guards
+	c, _ := NewConstraint("^1.0 || 1.2.3-rc.1")
+	if sc := c.Simplify(); sc != c {
+		t.Fatalf("unexpected simplified constraint: got: %q, want the original one", sc)
+	}
+}
+
+func TestSatisfying(t *testing.T) {
+	vs := make([]*Version, 0, 8)
+	for _, s := range []string{"1.2.3", "2.0.0", "1.5.0-beta", "1.0.0", "1.5.0", "0.9.0", "1.4.9", "3.0.0-rc.1"} {
+		vs = append(vs, newVersionUnsafe(s))
+	}
+	tests := []struct {
+		Constraint string
+		Max, Min   string
+		Filter     []string
+	}{
+		{Constraint: "^1.2", Max: "1.5.0", Min: "1.2.3", Filter: []string{"1.2.3", "1.5.0", "1.4.9"}},
+		{Constraint: ">=1.5.0-alpha, <2.0.0", Max: "1.5.0", Min: "1.5.0-beta", Filter: []string{"1.5.0-beta", "1.5.0"}},
+		{Constraint: "<1.0.0 || >=2.0.0", Max: "2.0.0", Min: "0.9.0", Filter: []string{"2.0.0", "0.9.0"}},
+		{Constraint: "^4.0.0"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.Constraint, func(t *testing.T) {
+			c, _ := NewConstraint(tt.Constraint)
+			str := func(v *Version) string {
+				if v == nil {
+					return ""
+				}
+				return v.String()
+			}
+			if max := str(c.MaxSatisfying(vs)); max != tt.Max {
+				t.Fatalf("unexpected max version: got: %q, want: %q", max, tt.Max)
+			}
+			if min := str(c.MinSatisfying(vs)); min != tt.Min {
+				t.Fatalf("unexpected min version: got: %q, want: %q", min, tt.Min)
+			}
+			var filter []string
+			for _, v := range c.FilterSatisfying(vs) {
+				filter = append(filter, v.String())
+			}
+			if !reflect.DeepEqual(filter, tt.Filter) {
+				t.Fatalf("unexpected filtered versions: got: %q, want: %q", filter, tt.Filter)
+			}
+		})
+	}
+}
+
+func TestConstraintText(t *testing.T) {
+	type lockfile struct {
+		Written    *Constraint
+		Normalized NormalizedConstraint
+	}
+
+	in := `{"Written":"~1.2 || >= 2.1 <2.5","Normalized":"^0.3"}`
+	var lf lockfile
+	if err := json.Unmarshal([]byte(in), &lf); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if s := lf.Written.Original(); s != "~1.2 || >= 2.1 <2.5" {
+		t.Fatalf("unexpected original constraint: got: %q", s)
+	}
+	var buf strings.Builder
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(lf); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expect := `{"Written":"~1.2 || >= 2.1 <2.5","Normalized":">=0.3.0, <0.4.0"}` + "\n"
+	if buf.String() != expect {
+		t.Fatalf("unexpected encoded lockfile: got: %s, want: %s", buf.String(), expect)
+	}
+
+	a, _ := NewConstraint(">=1.2.5")
+	c, _ := Intersect(a, lf.Written)
+	if b, _ := c.MarshalText(); string(b) != ">=1.2.5, <1.3.0 || >=2.1.0, <2.5.0" {
+		t.Fatalf("unexpected encoded intersection: got: %s", b)
+	}
+
+	gc, _ := NewConstraint("v1.0.0", WithDialect(DialectGo), WithPrereleasePolicy(PrereleaseExclude))
+	if err := gc.UnmarshalText([]byte("v1.2")); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if s, p := gc.String(), gc.PrereleasePolicy(); s != ">=1.2.0, <1.3.0" || p != PrereleaseExclude {
+		t.Fatalf("unexpected decoded constraint: %q, policy: %d", s, p)
+	}
+
+	buf.Reset()
+	if err := enc.Encode(lockfile{}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expect = `{"Written":null,"Normalized":""}` + "\n"
+	if buf.String() != expect {
+		t.Fatalf("unexpected encoded lockfile: got: %s, want: %s", buf.String(), expect)
+	}
+	if err := json.Unmarshal([]byte(expect), &lf); err != nil || lf.Normalized.Constraint != nil {
+		t.Fatalf("unexpected decoded constraint: %v, error: %v", lf.Normalized.Constraint, err)
+	}
+
+	// The normalized encoding grows with the number of ranges
+	clauses := []string{">=1.0.0"}
+	for i := 0; i < 18; i++ {
+		clauses = append(clauses, fmt.Sprintf("!=1.%d.0", i))
+	}
+	nc, _ := NewConstraint(strings.Join(clauses, ", "))
+	b, err := json.Marshal(NormalizedConstraint{nc})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if expect, _ := json.Marshal(nc.Simplify().String()); string(b) != string(expect) || len(b) > 20*40 {
+		t.Fatalf("unexpected encoded constraint of %d bytes: got: %.80s, want: %.80s", len(b), b, expect)
+	}
+	ic, _ := Intersect(nc, nc)
+	if b, _ := ic.MarshalText(); string(b) != nc.String() {
+		t.Fatalf("unexpected encoded intersection: got: %.80s, want: %.80s", b, nc.String())
+	}
+
+	err = json.Unmarshal([]byte(`{"Written":"<>1.2.3"}`), &lf)
+	var perr *ParseError
+	if !errors.As(err, &perr) || perr.Reason != ReasonUnknownOperator {
+		t.Fatalf("unexpected error: got: %v, want: %s", err, ReasonUnknownOperator)
+	}
+}
+
 type VerRes struct {
 	Ver string
 	Res bool
@@ -982,6 +1867,19 @@ func BenchmarkParseConstraintOnCheck(b *testing.B) {
 	})
 }
 
+func BenchmarkLargeOrCheck(b *testing.B) {
+	cs := make([]string, 0, 100)
+	for i := 0; i < 100; i++ {
+		cs = append(cs, fmt.Sprintf("~%d.%d", i/10, i%10))
+	}
+	c, _ := NewConstraint(strings.Join(cs, " || "))
+	v := newVersionUnsafe("9.9.9")
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		c.Check(v)
+	}
+}
+
 func BenchmarkSimpleCompare(b *testing.B) {
 	rand.Seed(time.Now().UTC().UnixNano())
 	n := 100000
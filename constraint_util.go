@@ -1,89 +1,159 @@
 package semver
 
-import "fmt"
+import "strings"
 
 type guardGen func([]uint32, uint8, string) (*Guard, *Guard, ConstraintUnion)
 
-var guardGens map[string]guardGen
-
-func init() {
-	guardGens = map[string]guardGen{
-		"":   genGuardTildeOrEqual,
-		"=":  genGuardTildeOrEqual,
-		"!=": genGuardNotEqual,
-		">":  genGuardGreaterThan,
-		">=": genGuardGreaterOrEqual,
-		"=>": genGuardGreaterOrEqual,
-		"<":  genGuardLessThan,
-		"<=": genGuardLessOrEqual,
-		"=<": genGuardLessOrEqual,
-		"~":  genGuardTilde,
-		"~>": genGuardTilde,
-		"^":  genGuardCaret,
-	}
+var guardGens = map[string]guardGen{
+	"":   genGuardTildeOrEqual,
+	"=":  genGuardTildeOrEqual,
+	"!=": genGuardNotEqual,
+	">":  genGuardGreaterThan,
+	">=": genGuardGreaterOrEqual,
+	"=>": genGuardGreaterOrEqual,
+	"<":  genGuardLessThan,
+	"<=": genGuardLessOrEqual,
+	"=<": genGuardLessOrEqual,
+	"~":  genGuardTilde,
+	"~>": genGuardTilde,
+	"^":  genGuardCaret,
 }
 
-func parseConstraint(s string) (*Constraint, error) {
+func parseConstraint(s string, d *dialect) (*Constraint, error) {
 	var left, right *Guard
 	var un ConstraintUnion
 
-	i, maxi := 0, len(s)
+	i := skipSpaces(s, 0)
+	op, j := readOpStr(s, i)
+	gen, ok := d.gens[op]
+	if !ok {
+		return nil, newParseError(ErrInvalidConstraint, s, i, j, ReasonUnknownOperator)
+	}
+	ds, wcds, pre, err := parseRange(s, j, d)
+	if err != nil {
+		return nil, err
+	}
+	if op != "" && len(ds) == 0 {
+		return nil, newParseError(ErrInvalidConstraint, s, i, j, ReasonMissingComponent)
+	}
+	if !d.pad {
+		wcds = widenMissing(ds, wcds)
+	}
+
+	left, right, un = gen(ds, wcds, pre)
+
+	return &Constraint{left: left, right: right, un: un}, nil
+}
+
+// parseHyphenRange parses an inclusive range of 2 versions separated with a
+// dash at position k: `1.2.3 - 2.3.4` is `>=1.2.3, <=2.3.4`. Partial versions
+// are expanded the same way as with the other operators: the lower bound
+// starts at the beginning of the range and the upper bound excludes anything
+// following the range: `1.2 - 2` is `>=1.2.0, <3.0.0`.
+func parseHyphenRange(s string, k int, d *dialect) (*Constraint, error) {
+	lds, lwcds, lpre, err := parseRange(s[:k], 0, d)
+	if err != nil {
+		return nil, rebaseError(err, s, 0)
+	}
+	hds, hwcds, hpre, err := parseRange(s, k+1, d)
+	if err != nil {
+		return nil, err
+	}
+
+	lo, _ := expandRange(lds, widenMissing(lds, lwcds), lpre)
+	hi, next := expandRange(hds, widenMissing(hds, hwcds), hpre)
+	hiGuard := NewGuard(hi, GuardLessOrEqual)
+	if hi != next {
+		hiGuard = NewGuard(next, GuardLessThan)
+	}
+
+	return &Constraint{
+		left:  NewGuard(lo, GuardGreaterOrEqual),
+		right: hiGuard,
+		un:    ConstraintUnionAnd,
+	}, nil
+}
+
+// parseRange parses a possibly partial version with optional wildcards
+// starting at position i: `1.2.*`, `1.x`, `1.2.3-beta.0`. Returns the version
+// numbers, the wildcard significance (see expandRange) and the pre-release
+// tag. Missing numbers are left to the caller, see widenMissing.
+func parseRange(s string, i int, d *dialect) ([]uint32, uint8, string, error) {
+	var reason ParseErrorReason
+	maxi := len(strings.TrimRight(s, " \t\n\v\f\r"))
 	ds := make([]uint32, 0, 3)
 	var wcds uint8
-	var d, dix int
-	i = skipTrailing(s, i)
-	var op, pre string
+	var n, dix, j int
+	var pre string
 
-	op, i = readOpStr(s, i)
-	if _, ok := guardGens[op]; !ok {
-		return nil, fmt.Errorf("unrecognised constraint operator: %q", op)
+	if d.prefix {
+		i = skipSpaces(s, i)
+		j = i + 1
+		if i >= maxi || s[i] != 'v' {
+			reason = ReasonUnexpectedChar
+			goto Err
+		}
+		i++
+	} else {
+		i = skipTrailing(s, i)
 	}
-	i = skipTrailing(s, i)
 	for i < maxi {
+		j = i + 1
 		if isNum(s[i]) {
+			n, j = readNum(s, i)
 			if dix >= 3 {
+				reason = ReasonTooManyComponents
 				goto Err
 			}
-			d, i = readNum(s, i)
-			if i == -1 {
+			if n == -1 || n > MaxComponent {
+				reason = ReasonOverflow
 				goto Err
 			}
-			ds = append(ds, uint32(d))
+			ds = append(ds, uint32(n))
 			dix++
-		} else if isStar(s[i]) {
+		} else if isStar(s[i]) && d.wildcards {
 			if dix >= 3 {
+				reason = ReasonTooManyComponents
 				goto Err
 			}
 			if w := uint8((1 << uint(2-dix))); w > wcds { // the most significant wildcard beats the rest
 				wcds = w
 			}
 			ds = append(ds, 0)
-			i++
 			dix++
 		} else {
+			reason = ReasonUnexpectedChar
 			goto Err
 		}
+		i = j
 		if i < maxi && isDot(s[i]) {
 			i++
 			continue
 		}
-		if i < maxi && isDash(s[i]) {
-			i++
-			pre, _ = readStr(s, i)
+		// Build metadata does not affect precedence and is ignored
+		if pre, _, j = readSuffix(s, i); j > i {
+			if isDash(s[i]) && pre == "" {
+				i, j = i+1, i+1
+				reason = ReasonEmptyIdentifier
+				goto Err
+			}
 			break
 		}
 	}
 
+	return ds, wcds, pre, nil
+Err:
+	return nil, 0, "", newParseError(ErrInvalidConstraint, s, i, j, reason)
+}
+
+// widenMissing raises the wildcard significance of a partial version:
+// `1.2` is `1.2.*`.
+func widenMissing(ds []uint32, wcds uint8) uint8 {
 	// Unset numbers are equivalent to wildcards
 	if w := uint8(3 - len(ds)); w > wcds {
-		wcds = w
+		return w
 	}
-
-	left, right, un = guardGens[op](ds, wcds, pre)
-
-	return &Constraint{left: left, right: right, un: un}, nil
-Err:
-	return nil, fmt.Errorf("failed to parse constraint %q around position %d", s, i)
+	return wcds
 }
 
 func compact(cs []*Constraint, un ConstraintUnion) *Constraint {
@@ -104,22 +174,25 @@ func compact(cs []*Constraint, un ConstraintUnion) *Constraint {
 }
 
 func expandRange(ds []uint32, wcds uint8, pre string) (*Version, *Version) {
-	v := NewVersionRaw(ds, pre)
+	v := newVersionRaw(ds, pre)
 	switch wcds {
 	case uint8(0):
 		return v, v
 	case uint8(1):
-		return &Version{base: v.base & 0x3FFFFC00}, v.NextMinor()
+		return &Version{base: v.base & (majorMask | minorMask)}, v.NextMinor()
 	case uint8(2):
-		return &Version{base: v.base & 0x3FF00000}, v.NextMajor()
+		return &Version{base: v.base & majorMask}, v.NextMajor()
 	default:
-		return &Version{base: 0}, &Version{base: 0x3FFFFFFF + 1}
+		return &Version{base: 0}, &Version{base: baseInf}
 	}
 }
 
 func genGuardNotEqual(ds []uint32, wcds uint8, pre string) (*Guard, *Guard, ConstraintUnion) {
 	v1, v2 := expandRange(ds, wcds, pre)
-	return NewGuard(v1, GuardLessThan), NewGuard(v2, GuardGreaterThan), ConstraintUnionOr
+	if v1 == v2 {
+		return NewGuard(v1, GuardLessThan), NewGuard(v2, GuardGreaterThan), ConstraintUnionOr
+	}
+	return NewGuard(v1, GuardLessThan), NewGuard(v2, GuardGreaterOrEqual), ConstraintUnionOr
 }
 
 func genGuardTildeOrEqual(ds []uint32, wcds uint8, pre string) (*Guard, *Guard, ConstraintUnion) {
@@ -155,7 +228,7 @@ func genGuardLessOrEqual(ds []uint32, wcds uint8, pre string) (*Guard, *Guard, C
 
 func genGuardTilde(ds []uint32, wcds uint8, pre string) (*Guard, *Guard, ConstraintUnion) {
 	var v1, v2 *Version
-	v1 = NewVersionRaw(ds, pre)
+	v1 = newVersionRaw(ds, pre)
 	if v1.base == 0 && v1.pre == "" {
 		return NewGuard(v1, GuardGreaterOrEqual), nil, ConstraintUnionOr
 	}
@@ -170,12 +243,12 @@ func genGuardTilde(ds []uint32, wcds uint8, pre string) (*Guard, *Guard, Constra
 
 func genGuardCaret(ds []uint32, wcds uint8, pre string) (*Guard, *Guard, ConstraintUnion) {
 	var v1, v2 *Version
-	v1 = NewVersionRaw(ds, pre)
+	v1 = newVersionRaw(ds, pre)
 
 	switch {
 	case wcds >= 4:
 		return NewGuard(v1, GuardGreaterOrEqual), nil, ConstraintUnionOr
-	case (v1.base & 0x3FFFFC00) == 0:
+	case (v1.base & (majorMask | minorMask)) == 0:
 		switch wcds {
 		case 0:
 			v2 = v1.NextPatch()
@@ -184,7 +257,7 @@ func genGuardCaret(ds []uint32, wcds uint8, pre string) (*Guard, *Guard, Constra
 		default:
 			v2 = v1.NextMajor()
 		}
-	case (v1.base & 0x3FF00000) == 0:
+	case (v1.base & majorMask) == 0:
 		v2 = v1.NextMinor()
 	default:
 		v2 = v1.NextMajor()
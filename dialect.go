@@ -0,0 +1,251 @@
+package semver
+
+// Dialect selects the grammar and the operator semantics of constraints of a
+// specific package ecosystem.
+type Dialect uint8
+
+const (
+	// DialectDefault is the native dialect of the library, see README.
+	DialectDefault Dialect = iota
+	// DialectNPM follows node-semver ranges: comparators are joined with
+	// whitespace and `||`, `<=1.2` includes the whole 1.2 family, there is
+	// no `!=`.
+	DialectNPM
+	// DialectCargo follows Cargo version requirements: a bare version is a
+	// caret requirement, comparators are joined with commas, there is no
+	// `||`.
+	DialectCargo
+	// DialectComposer follows Composer version constraints: partial versions
+	// are padded with zeros unless a wildcard is used, `~1.2` is
+	// `>=1.2.0, <2.0.0`, both `||` and `|` join alternatives.
+	DialectComposer
+	// DialectRuby follows RubyGems requirements: `~>` is the pessimistic
+	// operator, partial versions are padded with zeros, comparators are
+	// joined with commas.
+	DialectRuby
+	// DialectGo follows Go modules: versions require the `v` prefix, a bare
+	// version is a minimal requirement within its major version: `v1.2.3`
+	// is `>=1.2.3, <2.0.0`, a partial bare version is a prefix query: `v1.2`
+	// is `>=1.2.0, <1.3.0`.
+	DialectGo
+	// DialectPEP440 follows a subset of Python version specifiers: `==`,
+	// `!=`, `~=`, `===` and comparisons joined with commas. Pre-release and
+	// post-release spellings specific to PEP 440 are not supported.
+	DialectPEP440
+)
+
+var dialectNames = [...]string{
+	DialectDefault:  "default",
+	DialectNPM:      "npm",
+	DialectCargo:    "cargo",
+	DialectComposer: "composer",
+	DialectRuby:     "ruby",
+	DialectGo:       "go",
+	DialectPEP440:   "pep440",
+}
+
+func (d Dialect) String() string {
+	if int(d) < len(dialectNames) {
+		return dialectNames[d]
+	}
+	return "unknown"
+}
+
+// WithDialect sets the dialect a constraint is written in.
+func WithDialect(d Dialect) ConstraintOption {
+	return func(cfg *constraintConfig) {
+		cfg.dialect = d
+	}
+}
+
+// dialect describes the grammar of a constraint dialect.
+type dialect struct {
+	gens map[string]guardGen
+	// ors are the separators of alternatives, longest first
+	ors []string
+	// commaAnd and spaceAnd tell whether commas and whitespace join
+	// comparators
+	commaAnd, spaceAnd bool
+	hyphen             bool
+	wildcards          bool
+	// pad tells whether the generators get partial versions as they are
+	// rather than with missing numbers turned into wildcards
+	pad bool
+	// prefix tells whether versions must start with a `v`
+	prefix bool
+}
+
+var dialects = [...]*dialect{
+	DialectDefault: {
+		gens:      guardGens,
+		ors:       []string{"||"},
+		commaAnd:  true,
+		spaceAnd:  true,
+		hyphen:    true,
+		wildcards: true,
+	},
+	DialectNPM: {
+		gens: map[string]guardGen{
+			"":   genGuardTildeOrEqual,
+			"=":  genGuardTildeOrEqual,
+			">":  genGuardGreaterThan,
+			">=": genGuardGreaterOrEqual,
+			"<":  genGuardLessThan,
+			"<=": genGuardLessOrEqualRange,
+			"~":  genGuardTildeZero,
+			"~>": genGuardTildeZero,
+			"^":  genGuardCaret,
+		},
+		ors:       []string{"||"},
+		spaceAnd:  true,
+		hyphen:    true,
+		wildcards: true,
+	},
+	DialectCargo: {
+		gens: map[string]guardGen{
+			"":   genGuardCargoBare,
+			"=":  unpadded(genGuardTildeOrEqual),
+			">":  unpadded(genGuardGreaterThan),
+			">=": unpadded(genGuardGreaterOrEqual),
+			"<":  unpadded(genGuardLessThan),
+			"<=": unpadded(genGuardLessOrEqualRange),
+			"~":  unpadded(genGuardTildeZero),
+			"^":  unpadded(genGuardCaret),
+		},
+		commaAnd:  true,
+		wildcards: true,
+		pad:       true,
+	},
+	DialectComposer: {
+		gens: map[string]guardGen{
+			"":   genGuardTildeOrEqual,
+			"=":  genGuardTildeOrEqual,
+			"==": genGuardTildeOrEqual,
+			"!=": genGuardNotEqual,
+			"<>": genGuardNotEqual,
+			">":  genGuardGreaterThan,
+			">=": genGuardGreaterOrEqual,
+			"<":  genGuardLessThan,
+			"<=": genGuardLessOrEqual,
+			"~":  genGuardPessimistic,
+			"^":  unpadded(genGuardCaret),
+		},
+		ors:       []string{"||", "|"},
+		commaAnd:  true,
+		spaceAnd:  true,
+		hyphen:    true,
+		wildcards: true,
+		pad:       true,
+	},
+	DialectRuby: {
+		gens: map[string]guardGen{
+			"":   genGuardTildeOrEqual,
+			"=":  genGuardTildeOrEqual,
+			"!=": genGuardNotEqual,
+			">":  genGuardGreaterThan,
+			">=": genGuardGreaterOrEqual,
+			"<":  genGuardLessThan,
+			"<=": genGuardLessOrEqual,
+			"~>": genGuardPessimistic,
+		},
+		commaAnd: true,
+		pad:      true,
+	},
+	DialectGo: {
+		gens: map[string]guardGen{
+			"":   unpadded(genGuardGoRequire),
+			">":  genGuardGreaterThan,
+			">=": genGuardGreaterOrEqual,
+			"<":  genGuardLessThan,
+			"<=": genGuardLessOrEqual,
+		},
+		pad:    true,
+		prefix: true,
+	},
+	DialectPEP440: {
+		gens: map[string]guardGen{
+			"==":  genGuardTildeOrEqual,
+			"===": genGuardTildeOrEqual,
+			"!=":  genGuardNotEqual,
+			">":   genGuardGreaterThan,
+			">=":  genGuardGreaterOrEqual,
+			"<":   genGuardLessThan,
+			"<=":  genGuardLessOrEqual,
+			"~=":  genGuardPessimistic,
+		},
+		commaAnd:  true,
+		wildcards: true,
+		pad:       true,
+	},
+}
+
+// unpadded makes a guard generator of a dialect padding partial versions
+// with zeros treat missing numbers as wildcards.
+func unpadded(gen guardGen) guardGen {
+	return func(ds []uint32, wcds uint8, pre string) (*Guard, *Guard, ConstraintUnion) {
+		return gen(ds, widenMissing(ds, wcds), pre)
+	}
+}
+
+// genGuardCargoBare makes a bare version a caret requirement unless it has
+// wildcards: `1.2` is `^1.2`, while `1.2.*` is `>=1.2.0, <1.3.0`.
+func genGuardCargoBare(ds []uint32, wcds uint8, pre string) (*Guard, *Guard, ConstraintUnion) {
+	if wcds > 0 {
+		return genGuardTildeOrEqual(ds, widenMissing(ds, wcds), pre)
+	}
+	return genGuardCaret(ds, widenMissing(ds, wcds), pre)
+}
+
+// genGuardTildeZero is genGuardTilde reading the zero versions the way npm
+// and Cargo do: `~0` is `>=0.0.0, <1.0.0` and `~0.0.0` is `>=0.0.0, <0.1.0`
+// rather than `>=0.0.0`. Only `~*` matches any version.
+func genGuardTildeZero(ds []uint32, wcds uint8, pre string) (*Guard, *Guard, ConstraintUnion) {
+	v1 := newVersionRaw(ds, pre)
+	switch {
+	case wcds >= 4:
+		return NewGuard(v1, GuardGreaterOrEqual), nil, ConstraintUnionOr
+	case wcds > 1:
+		return NewGuard(v1, GuardGreaterOrEqual), NewGuard(v1.NextMajor(), GuardLessThan), ConstraintUnionAnd
+	}
+	return NewGuard(v1, GuardGreaterOrEqual), NewGuard(v1.NextMinor(), GuardLessThan), ConstraintUnionAnd
+}
+
+// genGuardLessOrEqualRange includes the entire range of a partial version:
+// `<=1.2` is `<1.3.0`.
+func genGuardLessOrEqualRange(ds []uint32, wcds uint8, pre string) (*Guard, *Guard, ConstraintUnion) {
+	v1, v2 := expandRange(ds, wcds, pre)
+	if v1 == v2 {
+		return NewGuard(v1, GuardLessOrEqual), nil, ConstraintUnionOr
+	}
+	return NewGuard(v2, GuardLessThan), nil, ConstraintUnionOr
+}
+
+// genGuardPessimistic lets the last specified number grow: `~>1.2` is
+// `>=1.2.0, <2.0.0`, `~>1.2.3` is `>=1.2.3, <1.3.0`. A single number behaves
+// as if the minor was specified: `~>1` is `>=1.0.0, <2.0.0`.
+func genGuardPessimistic(ds []uint32, wcds uint8, pre string) (*Guard, *Guard, ConstraintUnion) {
+	v1 := newVersionRaw(ds, pre)
+	var v2 *Version
+	if len(ds) < 3 {
+		v2 = v1.NextMajor()
+	} else {
+		v2 = v1.NextMinor()
+	}
+	return NewGuard(v1, GuardGreaterOrEqual), NewGuard(v2, GuardLessThan), ConstraintUnionAnd
+}
+
+// genGuardGoRequire accepts a version and any later one sharing its module
+// path: v0 and v1 share the path, while every following major version has a
+// path of its own.
+func genGuardGoRequire(ds []uint32, wcds uint8, pre string) (*Guard, *Guard, ConstraintUnion) {
+	v1, v2 := expandRange(ds, wcds, pre)
+	if v1 != v2 {
+		return NewGuard(v1, GuardGreaterOrEqual), NewGuard(v2, GuardLessThan), ConstraintUnionAnd
+	}
+	if v1.Major() == 0 {
+		v2 = v1.NextMajor().NextMajor()
+	} else {
+		v2 = v1.NextMajor()
+	}
+	return NewGuard(v1, GuardGreaterOrEqual), NewGuard(v2, GuardLessThan), ConstraintUnionAnd
+}
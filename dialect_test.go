@@ -0,0 +1,177 @@
+package semver
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestDialect(t *testing.T) {
+	tests := []struct {
+		Dialect   Dialect
+		Input     string
+		Expect    string
+		ExpectErr ParseErrorReason
+	}{
+		{Dialect: DialectNPM, Input: "<=1.2", Expect: "<1.3.0"},
+		{Dialect: DialectNPM, Input: "<=1.2.3", Expect: "<=1.2.3"},
+		{Dialect: DialectNPM, Input: ">=1.2.0 <2.0.0 || 3.x", Expect: ">=1.2.0, <2.0.0 || >=3.0.0, <4.0.0"},
+		{Dialect: DialectNPM, Input: "1.2.3 - 2.3", Expect: ">=1.2.3, <2.4.0"},
+		{Dialect: DialectNPM, Input: "~0", Expect: ">=0.0.0, <1.0.0"},
+		{Dialect: DialectNPM, Input: "~0.0", Expect: ">=0.0.0, <0.1.0"},
+		{Dialect: DialectNPM, Input: "~0.0.0", Expect: ">=0.0.0, <0.1.0"},
+		{Dialect: DialectNPM, Input: "~1", Expect: ">=1.0.0, <2.0.0"},
+		{Dialect: DialectNPM, Input: "~1.2.3", Expect: ">=1.2.3, <1.3.0"},
+		{Dialect: DialectNPM, Input: "~> 1.2", Expect: ">=1.2.0, <1.3.0"},
+		{Dialect: DialectNPM, Input: "^0.0.3", Expect: ">=0.0.3, <0.0.4"},
+		{Dialect: DialectNPM, Input: "^0.0", Expect: ">=0.0.0, <0.1.0"},
+		{Dialect: DialectNPM, Input: "^0.x", Expect: ">=0.0.0, <1.0.0"},
+		{Dialect: DialectNPM, Input: "^1.2.x", Expect: ">=1.2.0, <2.0.0"},
+		{Dialect: DialectNPM, Input: "1.2.x", Expect: ">=1.2.0, <1.3.0"},
+		{Dialect: DialectNPM, Input: "*", Expect: ">=0.0.0"},
+		{Dialect: DialectNPM, Input: "", Expect: ">=0.0.0"},
+		{Dialect: DialectNPM, Input: ">1.2", Expect: ">=1.3.0"},
+		{Dialect: DialectNPM, Input: "<1.2", Expect: "<1.2.0"},
+		{Dialect: DialectNPM, Input: "1.2 - 2.3.4", Expect: ">=1.2.0, <=2.3.4"},
+		{Dialect: DialectNPM, Input: "v1.2.3", Expect: "=1.2.3"},
+		{Dialect: DialectNPM, Input: ">= 1.2.3", Expect: ">=1.2.3"},
+		{Dialect: DialectNPM, Input: "!=1.2.3", ExpectErr: ReasonUnknownOperator},
+		{Dialect: DialectNPM, Input: "1.2.3 -", ExpectErr: ReasonUnexpectedChar},
+		{Dialect: DialectNPM, Input: ">=1.2.0, <2.0.0", ExpectErr: ReasonUnexpectedChar},
+
+		{Dialect: DialectCargo, Input: "1.2.3", Expect: ">=1.2.3, <2.0.0"},
+		{Dialect: DialectCargo, Input: "0.2", Expect: ">=0.2.0, <0.3.0"},
+		{Dialect: DialectCargo, Input: "=1.2.3", Expect: "=1.2.3"},
+		{Dialect: DialectCargo, Input: ">=1.2.0, <1.5.0", Expect: ">=1.2.0, <1.5.0"},
+		{Dialect: DialectCargo, Input: "1.2.*", Expect: ">=1.2.0, <1.3.0"},
+		{Dialect: DialectCargo, Input: "=1.2", Expect: ">=1.2.0, <1.3.0"},
+		{Dialect: DialectCargo, Input: "<=1.2", Expect: "<1.3.0"},
+		{Dialect: DialectCargo, Input: "~0", Expect: ">=0.0.0, <1.0.0"},
+		{Dialect: DialectCargo, Input: "~0.0", Expect: ">=0.0.0, <0.1.0"},
+		{Dialect: DialectCargo, Input: "~0.0.0", Expect: ">=0.0.0, <0.1.0"},
+		{Dialect: DialectCargo, Input: "~1", Expect: ">=1.0.0, <2.0.0"},
+		{Dialect: DialectCargo, Input: "~1.2", Expect: ">=1.2.0, <1.3.0"},
+		{Dialect: DialectCargo, Input: "0.0.3", Expect: ">=0.0.3, <0.0.4"},
+		{Dialect: DialectCargo, Input: "0.0", Expect: ">=0.0.0, <0.1.0"},
+		{Dialect: DialectCargo, Input: "0", Expect: ">=0.0.0, <1.0.0"},
+		{Dialect: DialectCargo, Input: "^1", Expect: ">=1.0.0, <2.0.0"},
+		{Dialect: DialectCargo, Input: "*", Expect: ">=0.0.0"},
+		{Dialect: DialectCargo, Input: "1.*", Expect: ">=1.0.0, <2.0.0"},
+		{Dialect: DialectCargo, Input: "=1", Expect: ">=1.0.0, <2.0.0"},
+		{Dialect: DialectCargo, Input: ">1", Expect: ">=2.0.0"},
+		{Dialect: DialectCargo, Input: ">=1.2, <1.5", Expect: ">=1.2.0, <1.5.0"},
+		{Dialect: DialectCargo, Input: "1.2.3-alpha.1", Expect: ">=1.2.3-alpha.1, <2.0.0"},
+		{Dialect: DialectCargo, Input: "1.2.3 || 2.0.0", ExpectErr: ReasonUnexpectedChar},
+		{Dialect: DialectCargo, Input: "1.2.3 - 2.0.0", ExpectErr: ReasonUnexpectedChar},
+		{Dialect: DialectCargo, Input: "!=1.2.3", ExpectErr: ReasonUnknownOperator},
+		{Dialect: DialectCargo, Input: ">=1.2.0 <1.5.0", ExpectErr: ReasonUnexpectedChar},
+
+		{Dialect: DialectComposer, Input: "1.2", Expect: "=1.2.0"},
+		{Dialect: DialectComposer, Input: "1.2.*", Expect: ">=1.2.0, <1.3.0"},
+		{Dialect: DialectComposer, Input: "~1.2", Expect: ">=1.2.0, <2.0.0"},
+		{Dialect: DialectComposer, Input: "~1.2.3", Expect: ">=1.2.3, <1.3.0"},
+		{Dialect: DialectComposer, Input: "^0.3", Expect: ">=0.3.0, <0.4.0"},
+		{Dialect: DialectComposer, Input: "1.0 - 2.0", Expect: ">=1.0.0, <2.1.0"},
+		{Dialect: DialectComposer, Input: "^1.0 | ^2.0", Expect: ">=1.0.0, <3.0.0"},
+		{Dialect: DialectComposer, Input: ">=1.0 <1.1 || >=1.2", Expect: ">=1.0.0, <1.1.0 || >=1.2.0"},
+		{Dialect: DialectComposer, Input: "1.0.*", Expect: ">=1.0.0, <1.1.0"},
+		{Dialect: DialectComposer, Input: "~1", Expect: ">=1.0.0, <2.0.0"},
+		{Dialect: DialectComposer, Input: "^1.2.3", Expect: ">=1.2.3, <2.0.0"},
+		{Dialect: DialectComposer, Input: "^0.0.3", Expect: ">=0.0.3, <0.0.4"},
+		{Dialect: DialectComposer, Input: "!=1.0", Expect: "<1.0.0 || >1.0.0"},
+		{Dialect: DialectComposer, Input: "<>1.2.3", Expect: "<1.2.3 || >1.2.3"},
+		{Dialect: DialectComposer, Input: "1.0.0 - 2.1.0", Expect: ">=1.0.0, <=2.1.0"},
+		{Dialect: DialectComposer, Input: "*", Expect: ">=0.0.0"},
+		{Dialect: DialectComposer, Input: ">=1.0,<2.0", Expect: ">=1.0.0, <2.0.0"},
+		{Dialect: DialectComposer, Input: "~>1.2", ExpectErr: ReasonUnknownOperator},
+
+		{Dialect: DialectRuby, Input: "~> 1.2", Expect: ">=1.2.0, <2.0.0"},
+		{Dialect: DialectRuby, Input: "~> 1.2.3", Expect: ">=1.2.3, <1.3.0"},
+		{Dialect: DialectRuby, Input: ">= 1.0, < 2", Expect: ">=1.0.0, <2.0.0"},
+		{Dialect: DialectRuby, Input: "= 1.2", Expect: "=1.2.0"},
+		{Dialect: DialectRuby, Input: "~> 1.0", Expect: ">=1.0.0, <2.0.0"},
+		{Dialect: DialectRuby, Input: "~> 1", Expect: ">=1.0.0, <2.0.0"},
+		{Dialect: DialectRuby, Input: "~> 0.3.2", Expect: ">=0.3.2, <0.4.0"},
+		{Dialect: DialectRuby, Input: "!= 1.2", Expect: "<1.2.0 || >1.2.0"},
+		{Dialect: DialectRuby, Input: "> 1.2", Expect: ">1.2.0"},
+		{Dialect: DialectRuby, Input: "<= 2", Expect: "<=2.0.0"},
+		{Dialect: DialectRuby, Input: "1.2.3", Expect: "=1.2.3"},
+		{Dialect: DialectRuby, Input: "1.x", ExpectErr: ReasonUnexpectedChar},
+		{Dialect: DialectRuby, Input: "~1.2", ExpectErr: ReasonUnknownOperator},
+		{Dialect: DialectRuby, Input: "^1.2", ExpectErr: ReasonUnknownOperator},
+		{Dialect: DialectRuby, Input: ">= 1.0 < 2.0", ExpectErr: ReasonUnexpectedChar},
+		{Dialect: DialectRuby, Input: "1.2.3 - 2", ExpectErr: ReasonUnexpectedChar},
+		{Dialect: DialectRuby, Input: "1.2.3 || 2.0.0", ExpectErr: ReasonUnexpectedChar},
+
+		{Dialect: DialectGo, Input: "v1.2.3", Expect: ">=1.2.3, <2.0.0"},
+		{Dialect: DialectGo, Input: "v0.3.0", Expect: ">=0.3.0, <2.0.0"},
+		{Dialect: DialectGo, Input: "v2.1.0", Expect: ">=2.1.0, <3.0.0"},
+		{Dialect: DialectGo, Input: "v1.2", Expect: ">=1.2.0, <1.3.0"},
+		{Dialect: DialectGo, Input: "<v1.2", Expect: "<1.2.0"},
+		{Dialect: DialectGo, Input: "v1.2.3-pre", Expect: ">=1.2.3-pre, <2.0.0"},
+		{Dialect: DialectGo, Input: "v1", Expect: ">=1.0.0, <2.0.0"},
+		{Dialect: DialectGo, Input: "v0", Expect: ">=0.0.0, <1.0.0"},
+		{Dialect: DialectGo, Input: ">=v1.2.3", Expect: ">=1.2.3"},
+		{Dialect: DialectGo, Input: ">v1.2", Expect: ">1.2.0"},
+		{Dialect: DialectGo, Input: "<=v1.2.3", Expect: "<=1.2.3"},
+		{Dialect: DialectGo, Input: "1.2.3", ExpectErr: ReasonUnexpectedChar},
+		{Dialect: DialectGo, Input: "v1.x", ExpectErr: ReasonUnexpectedChar},
+		{Dialect: DialectGo, Input: ">=v1.0.0 <v2.0.0", ExpectErr: ReasonUnexpectedChar},
+		{Dialect: DialectGo, Input: "v1.0.0, v2.0.0", ExpectErr: ReasonUnexpectedChar},
+		{Dialect: DialectGo, Input: "v1.0.0 || v2.0.0", ExpectErr: ReasonUnexpectedChar},
+		{Dialect: DialectGo, Input: "=v1.2.3", ExpectErr: ReasonUnknownOperator},
+		{Dialect: DialectGo, Input: "^v1.2.3", ExpectErr: ReasonUnknownOperator},
+
+		{Dialect: DialectPEP440, Input: "~=1.2", Expect: ">=1.2.0, <2.0.0"},
+		{Dialect: DialectPEP440, Input: "~=1.2.3", Expect: ">=1.2.3, <1.3.0"},
+		{Dialect: DialectPEP440, Input: "==1.2.*", Expect: ">=1.2.0, <1.3.0"},
+		{Dialect: DialectPEP440, Input: "==1.2", Expect: "=1.2.0"},
+		{Dialect: DialectPEP440, Input: ">=1.0, !=1.5.0, <2", Expect: ">=1.0.0, <1.5.0 || >1.5.0, <2.0.0"},
+		{Dialect: DialectPEP440, Input: "===1.2.3", Expect: "=1.2.3"},
+		{Dialect: DialectPEP440, Input: "~=2.2", Expect: ">=2.2.0, <3.0.0"},
+		{Dialect: DialectPEP440, Input: "~=1.4.5", Expect: ">=1.4.5, <1.5.0"},
+		{Dialect: DialectPEP440, Input: "==1.*", Expect: ">=1.0.0, <2.0.0"},
+		{Dialect: DialectPEP440, Input: "!=1.2.*", Expect: "<1.2.0 || >=1.3.0"},
+		{Dialect: DialectPEP440, Input: ">1.2", Expect: ">1.2.0"},
+		{Dialect: DialectPEP440, Input: "<=2", Expect: "<=2.0.0"},
+		{Dialect: DialectPEP440, Input: ">=1.0,<2.0", Expect: ">=1.0.0, <2.0.0"},
+		{Dialect: DialectPEP440, Input: "1.2.3", ExpectErr: ReasonUnknownOperator},
+		{Dialect: DialectPEP440, Input: "^1.2", ExpectErr: ReasonUnknownOperator},
+		{Dialect: DialectPEP440, Input: "~1.2", ExpectErr: ReasonUnknownOperator},
+		{Dialect: DialectPEP440, Input: ">=1.0 <2.0", ExpectErr: ReasonUnexpectedChar},
+		{Dialect: DialectPEP440, Input: ">=1.0 || <0.5", ExpectErr: ReasonUnexpectedChar},
+
+		{Dialect: DialectDefault, Input: "1.2", Expect: ">=1.2.0, <1.3.0"},
+		{Dialect: DialectDefault, Input: "~0", Expect: ">=0.0.0"},
+		{Dialect: DialectDefault, Input: "~0.0.0", Expect: ">=0.0.0"},
+		{Dialect: DialectDefault, Input: "~0.1", Expect: ">=0.1.0, <0.2.0"},
+		{Dialect: DialectDefault, Input: "~*", Expect: ">=0.0.0"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.Dialect.String()+"/"+tt.Input, func(t *testing.T) {
+			c, err := NewConstraint(tt.Input, WithDialect(tt.Dialect))
+			if tt.Expect == "" {
+				var perr *ParseError
+				if !errors.As(err, &perr) || perr.Reason != tt.ExpectErr {
+					t.Fatalf("unexpected error: got: %v, want: %s", err, tt.ExpectErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if s := c.String(); s != tt.Expect {
+				t.Fatalf("unexpected constraint string: got: %q, want: %q", s, tt.Expect)
+			}
+		})
+	}
+}
+
+func TestUnknownDialect(t *testing.T) {
+	if _, err := NewConstraint("1.2.3", WithDialect(Dialect(255))); !errors.Is(err, ErrInvalidConstraint) {
+		t.Fatalf("unexpected error: got: %v, want: %v", err, ErrInvalidConstraint)
+	}
+	if s := Dialect(255).String(); s != "unknown" {
+		t.Fatalf("unexpected dialect name: got: %q, want: %q", s, "unknown")
+	}
+}
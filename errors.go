@@ -1,7 +1,79 @@
 package semver
 
-import "errors"
+import (
+	"errors"
+	"fmt"
+)
 
 var (
-	ErrInvalidSemVer = errors.New("Invalid Semantic Version")
+	ErrInvalidSemVer     = errors.New("Invalid Semantic Version")
+	ErrInvalidConstraint = errors.New("Invalid Constraint")
+	ErrOverflow          = errors.New("Version Number Out Of Range")
+)
+
+// ParseErrorReason explains why a version or a constraint was rejected.
+type ParseErrorReason uint8
+
+const (
+	ReasonUnexpectedChar ParseErrorReason = iota
+	ReasonMissingComponent
+	ReasonTooManyComponents
+	ReasonLeadingZero
+	ReasonOverflow
+	ReasonEmptyIdentifier
+	ReasonUnknownOperator
 )
+
+var reasonNames = [...]string{
+	ReasonUnexpectedChar:    "unexpected character",
+	ReasonMissingComponent:  "missing version component",
+	ReasonTooManyComponents: "too many version components",
+	ReasonLeadingZero:       "leading zero",
+	ReasonOverflow:          "number out of range",
+	ReasonEmptyIdentifier:   "empty identifier",
+	ReasonUnknownOperator:   "unknown operator",
+}
+
+func (r ParseErrorReason) String() string {
+	if int(r) < len(reasonNames) {
+		return reasonNames[r]
+	}
+	return fmt.Sprintf("ParseErrorReason(%d)", uint8(r))
+}
+
+// ParseError describes a failure to parse a version or a constraint.
+// Offset is the byte offset of the offending Token in Input. The error wraps
+// either ErrInvalidSemVer or ErrInvalidConstraint, which can be tested with
+// errors.Is. Errors with ReasonOverflow also match ErrOverflow.
+type ParseError struct {
+	Input  string
+	Offset int
+	Token  string
+	Reason ParseErrorReason
+	Err    error
+}
+
+func newParseError(err error, s string, i, j int, reason ParseErrorReason) *ParseError {
+	return &ParseError{
+		Input:  s,
+		Offset: i,
+		Token:  s[i:j],
+		Reason: reason,
+		Err:    err,
+	}
+}
+
+func (e *ParseError) Error() string {
+	if len(e.Token) == 0 {
+		return fmt.Sprintf("%s %q: %s at position %d", e.Err, e.Input, e.Reason, e.Offset)
+	}
+	return fmt.Sprintf("%s %q: %s %q at position %d", e.Err, e.Input, e.Reason, e.Token, e.Offset)
+}
+
+func (e *ParseError) Unwrap() error {
+	return e.Err
+}
+
+func (e *ParseError) Is(target error) bool {
+	return target == ErrOverflow && e.Reason == ReasonOverflow
+}
@@ -1,5 +1,5 @@
 module sandbox/semver
 
-go 1.12
+go 1.13
 
 require github.com/Masterminds/semver v1.5.0
@@ -1,5 +1,7 @@
 package semver
 
+import "strconv"
+
 type GuardEquality uint8
 
 const (
@@ -22,14 +24,48 @@ func NewGuard(ver *Version, op GuardEquality) *Guard {
 	}
 }
 
+var guardOps = [...]string{
+	GuardEqual:          "=",
+	GuardGreaterThan:    ">",
+	GuardGreaterOrEqual: ">=",
+	GuardLessThan:       "<",
+	GuardLessOrEqual:    "<=",
+}
+
+func (op GuardEquality) String() string {
+	if int(op) < len(guardOps) {
+		return guardOps[op]
+	}
+	return "GuardEquality(" + strconv.Itoa(int(op)) + ")"
+}
+
+// String renders the guard as an operator followed by a version: `>=1.2.3`.
+// A guard matching any version is rendered as `*` and a guard matching none
+// as `<0.0.0-0`.
+func (g *Guard) String() string {
+	if g.ver.isInf() {
+		switch g.op {
+		case GuardLessThan, GuardLessOrEqual:
+			return "*"
+		default:
+			return "<0.0.0-0"
+		}
+	}
+	return g.op.String() + g.ver.String()
+}
+
+// Check tells whether v satisfies the guard. A nil guard matches no version.
 func (g *Guard) Check(v *Version) bool {
+	if g == nil {
+		return false
+	}
 	eq := g.ver.Equal(v)
 	less := !eq && v.Less(g.ver)
 	switch g.op {
 	case GuardEqual:
 		return eq
 	case GuardGreaterThan:
-		return !less
+		return !eq && !less
 	case GuardGreaterOrEqual:
 		return eq || !less
 	case GuardLessThan:
@@ -0,0 +1,164 @@
+package semver
+
+import (
+	"math/rand"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// refCompare is a deliberately naive reference implementation of the SemVer
+// 2.0.0 precedence rules used as an oracle for the property tests.
+func refCompare(v1, v2 string) int {
+	strip := func(v string) (string, string) {
+		if i := strings.IndexByte(v, '+'); i >= 0 {
+			v = v[:i]
+		}
+		if i := strings.IndexByte(v, '-'); i >= 0 {
+			return v[:i], v[i+1:]
+		}
+		return v, ""
+	}
+	cmpNum := func(n1, n2 uint64) int {
+		switch {
+		case n1 < n2:
+			return -1
+		case n1 > n2:
+			return 1
+		}
+		return 0
+	}
+
+	rel1, pre1 := strip(v1)
+	rel2, pre2 := strip(v2)
+	ds1, ds2 := strings.Split(rel1, "."), strings.Split(rel2, ".")
+	for i := range ds1 {
+		n1, _ := strconv.ParseUint(ds1[i], 10, 64)
+		n2, _ := strconv.ParseUint(ds2[i], 10, 64)
+		if c := cmpNum(n1, n2); c != 0 {
+			return c
+		}
+	}
+
+	switch {
+	case pre1 == "" && pre2 == "":
+		return 0
+	case pre1 == "":
+		return 1
+	case pre2 == "":
+		return -1
+	}
+	ids1, ids2 := strings.Split(pre1, "."), strings.Split(pre2, ".")
+	for i := 0; i < len(ids1) && i < len(ids2); i++ {
+		n1, err1 := strconv.ParseUint(ids1[i], 10, 64)
+		n2, err2 := strconv.ParseUint(ids2[i], 10, 64)
+		switch {
+		case err1 == nil && err2 == nil:
+			if c := cmpNum(n1, n2); c != 0 {
+				return c
+			}
+		case err1 == nil:
+			return -1
+		case err2 == nil:
+			return 1
+		default:
+			if c := strings.Compare(ids1[i], ids2[i]); c != 0 {
+				return c
+			}
+		}
+	}
+	return cmpNum(uint64(len(ids1)), uint64(len(ids2)))
+}
+
+// randVersion generates versions from a small domain, so that equal numbers
+// and pre-release tags sharing a prefix are frequent.
+func randVersion(r *rand.Rand) string {
+	pres := []string{
+		"", "", "", "0", "1", "2", "10", "alpha", "alpha.1", "alpha.2",
+		"alpha.10", "alpha.beta", "alpha-1", "beta", "beta.2", "beta.11",
+		"rc.1", "rc.1.0", "A", "a", "0a", "1-0",
+	}
+	v := strconv.Itoa(r.Intn(3)) + "." + strconv.Itoa(r.Intn(3)) + "." + strconv.Itoa(r.Intn(3))
+	if pre := pres[r.Intn(len(pres))]; pre != "" {
+		v += "-" + pre
+	}
+	if r.Intn(4) == 0 {
+		v += "+build." + strconv.Itoa(r.Intn(3))
+	}
+	return v
+}
+
+func TestGuardProperties(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	expect := map[GuardEquality]func(int) bool{
+		GuardEqual:          func(c int) bool { return c == 0 },
+		GuardGreaterThan:    func(c int) bool { return c > 0 },
+		GuardGreaterOrEqual: func(c int) bool { return c >= 0 },
+		GuardLessThan:       func(c int) bool { return c < 0 },
+		GuardLessOrEqual:    func(c int) bool { return c <= 0 },
+	}
+
+	for n := 0; n < 20000; n++ {
+		s1, s2 := randVersion(r), randVersion(r)
+		v1, v2 := newVersionUnsafe(s1), newVersionUnsafe(s2)
+		c := refCompare(s2, s1)
+
+		if less, eq := v2.Less(v1), v2.Equal(v1); less != (c < 0) || eq != (c == 0) {
+			t.Fatalf("unexpected comparison of %s and %s: less: %t, equal: %t, reference: %d", s2, s1, less, eq, c)
+		}
+		for op, want := range expect {
+			if got := NewGuard(v1, op).Check(v2); got != want(c) {
+				t.Fatalf("unexpected check of %s against %s%s: got: %t, want: %t", s2, op, s1, got, want(c))
+			}
+		}
+	}
+}
+
+func TestGuardConstraintProperties(t *testing.T) {
+	r := rand.New(rand.NewSource(2))
+	expect := map[string]func(int) bool{
+		"=":  func(c int) bool { return c == 0 },
+		"!=": func(c int) bool { return c != 0 },
+		">":  func(c int) bool { return c > 0 },
+		">=": func(c int) bool { return c >= 0 },
+		"<":  func(c int) bool { return c < 0 },
+		"<=": func(c int) bool { return c <= 0 },
+	}
+
+	for n := 0; n < 5000; n++ {
+		s1, s2 := randVersion(r), randVersion(r)
+		v2 := newVersionUnsafe(s2)
+		c := refCompare(s2, s1)
+		for op, want := range expect {
+			cs, err := NewConstraint(op+s1, WithPrereleasePolicy(PrereleaseInclude))
+			if err != nil {
+				t.Fatalf("unexpected error parsing %s%s: %v", op, s1, err)
+			}
+			if got := cs.Check(v2); got != want(c) {
+				t.Fatalf("unexpected check of %s against %s%s: got: %t, want: %t", s2, op, s1, got, want(c))
+			}
+		}
+	}
+}
+
+func TestGuardGreaterThan(t *testing.T) {
+	g := NewGuard(newVersionUnsafe("1.2.3"), GuardGreaterThan)
+	tests := []struct {
+		Version string
+		Expect  bool
+	}{
+		{Version: "1.2.3", Expect: false},
+		{Version: "1.2.3+build.5", Expect: false},
+		{Version: "1.2.3-rc.1", Expect: false},
+		{Version: "1.2.4-0", Expect: true},
+		{Version: "1.2.4", Expect: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.Version, func(t *testing.T) {
+			if check := g.Check(newVersionUnsafe(tt.Version)); check != tt.Expect {
+				t.Fatalf("unexpected check result for %q: got: %t, want: %t", tt.Version, check, tt.Expect)
+			}
+		})
+	}
+}
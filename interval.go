@@ -0,0 +1,344 @@
+package semver
+
+import "sort"
+
+// Interval is a half-open range of versions [Lo, Hi). Exclusive lower and
+// inclusive upper bounds are expressed with the least following version:
+// `>1.2.3` starts at `1.2.4-0`. Lo is `0.0.0-0`, the least version, if the
+// interval is unbounded below, and Hi is nil if it is unbounded above.
+//
+// Internally an interval unbounded above ends at the version following all
+// the representable ones.
+type Interval struct {
+	Lo, Hi *Version
+}
+
+var (
+	verMin  = &Version{base: 0, pre: "0"}
+	verZero = &Version{base: 0}
+	verInf  = &Version{base: baseInf}
+)
+
+// succ returns the least version following v: the successor of `1.2.3` is
+// `1.2.4-0`, the successor of `1.2.3-rc` is `1.2.3-rc.0`.
+func succ(v *Version) *Version {
+	switch {
+	case v.isInf():
+		return v
+	case len(v.pre) > 0:
+		return &Version{base: v.base, pre: v.pre + ".0"}
+	}
+	return &Version{base: v.base + 1, pre: "0"}
+}
+
+// pred is the inverse of succ. Returns nil if v is not a successor of any
+// version or if the predecessor would borrow from the minor or major number:
+// `<1.3.0-0` must not turn into `<=1.2.2097151`.
+func pred(v *Version) *Version {
+	switch n := len(v.pre); {
+	case v.pre == "0" && v.base&patchMask > 0:
+		return &Version{base: v.base - 1}
+	case n > 2 && v.pre[n-2:] == ".0":
+		return &Version{base: v.base, pre: v.pre[:n-2]}
+	}
+	return nil
+}
+
+// guardIntervals returns the versions matching g.
+func guardIntervals(g *Guard) []Interval {
+	if g == nil {
+		return nil
+	}
+	var iv Interval
+	switch g.op {
+	case GuardEqual:
+		iv = Interval{g.ver, succ(g.ver)}
+	case GuardGreaterThan:
+		iv = Interval{succ(g.ver), verInf}
+	case GuardGreaterOrEqual:
+		iv = Interval{g.ver, verInf}
+	case GuardLessThan:
+		iv = Interval{verMin, g.ver}
+	case GuardLessOrEqual:
+		iv = Interval{verMin, succ(g.ver)}
+	}
+	if !iv.Lo.Less(iv.Hi) {
+		return nil
+	}
+	return []Interval{iv}
+}
+
+// checkerIntervals converts a constraint tree into a sorted list of disjoint
+// intervals ignoring the pre-release policy.
+func checkerIntervals(ch Checker) []Interval {
+	switch c := ch.(type) {
+	case *Guard:
+		return guardIntervals(c)
+	case *Constraint:
+		if c == nil {
+			return nil
+		}
+		l, r := checkerIntervals(c.left), checkerIntervals(c.right)
+		if c.un == ConstraintUnionAnd {
+			return intersectIntervals(l, r)
+		}
+		return unionIntervals(l, r)
+	}
+	panic("should not happen")
+}
+
+func intersectIntervals(a, b []Interval) []Interval {
+	var ivs []Interval
+	i, j := 0, 0
+	for i < len(a) && j < len(b) {
+		lo, hi := a[i].Lo, a[i].Hi
+		if lo.Less(b[j].Lo) {
+			lo = b[j].Lo
+		}
+		if b[j].Hi.Less(hi) {
+			hi = b[j].Hi
+		}
+		if lo.Less(hi) {
+			ivs = append(ivs, Interval{lo, hi})
+		}
+		// The interval ending first can not overlap any of the following
+		if a[i].Hi.Less(b[j].Hi) {
+			i++
+		} else {
+			j++
+		}
+	}
+	return ivs
+}
+
+func unionIntervals(a, b []Interval) []Interval {
+	ivs := make([]Interval, 0, len(a)+len(b))
+	i, j := 0, 0
+	for i < len(a) || j < len(b) {
+		var iv Interval
+		if j == len(b) || (i < len(a) && a[i].Lo.Less(b[j].Lo)) {
+			iv = a[i]
+			i++
+		} else {
+			iv = b[j]
+			j++
+		}
+		// Overlapping and adjacent intervals are merged
+		if n := len(ivs); n > 0 && !ivs[n-1].Hi.Less(iv.Lo) {
+			if ivs[n-1].Hi.Less(iv.Hi) {
+				ivs[n-1].Hi = iv.Hi
+			}
+			continue
+		}
+		ivs = append(ivs, iv)
+	}
+	return ivs
+}
+
+// searchIntervals tells whether v falls into any of the intervals.
+func searchIntervals(ivs []Interval, v *Version) bool {
+	i := sort.Search(len(ivs), func(i int) bool { return v.Less(ivs[i].Hi) })
+	return i < len(ivs) && !v.Less(ivs[i].Lo)
+}
+
+func equalIntervals(a, b []Interval) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if !a[i].Lo.Equal(b[i].Lo) || !a[i].Hi.Equal(b[i].Hi) {
+			return false
+		}
+	}
+	return true
+}
+
+// complementIntervals returns the versions not matched by the intervals.
+func complementIntervals(a []Interval) []Interval {
+	var ivs []Interval
+	lo := verMin
+	for _, iv := range a {
+		if lo.Less(iv.Lo) {
+			ivs = append(ivs, Interval{lo, iv.Lo})
+		}
+		lo = iv.Hi
+	}
+	if lo.Less(verInf) {
+		ivs = append(ivs, Interval{lo, verInf})
+	}
+	return ivs
+}
+
+// intervalGuards returns the guards matching the versions of the interval,
+// preferring the operators a constraint would be written with: `>1.2.3`
+// rather than `>=1.2.4-0`. A bound `X-0` of a pre-release tuple X listed in
+// bases is kept as is, so that the guards keep referring to the tuple.
+func intervalGuards(iv Interval, bases []uint64) []*Guard {
+	if p := pred(iv.Hi); p != nil && p.Equal(iv.Lo) {
+		return []*Guard{NewGuard(iv.Lo, GuardEqual)}
+	}
+	var gs []*Guard
+	if !iv.Lo.Equal(verMin) {
+		if p := pred(iv.Lo); p != nil && !isTupleStart(iv.Lo, bases) {
+			gs = append(gs, NewGuard(p, GuardGreaterThan))
+		} else {
+			gs = append(gs, NewGuard(iv.Lo, GuardGreaterOrEqual))
+		}
+	}
+	if !iv.Hi.isInf() {
+		if p := pred(iv.Hi); p != nil && !isTupleStart(iv.Hi, bases) {
+			gs = append(gs, NewGuard(p, GuardLessOrEqual))
+		} else {
+			gs = append(gs, NewGuard(iv.Hi, GuardLessThan))
+		}
+	}
+	if len(gs) == 0 {
+		// Unlike `*`, which starts at `0.0.0`, it matches the pre-releases of 0.0.0
+		gs = append(gs, NewGuard(verMin, GuardGreaterOrEqual))
+	}
+	return gs
+}
+
+// isTupleStart tells whether v is `X-0` of a tuple X listed in bases.
+func isTupleStart(v *Version, bases []uint64) bool {
+	return v.pre == "0" && containsBase(bases, v.base)
+}
+
+func containsBase(bases []uint64, base uint64) bool {
+	for _, b := range bases {
+		if b == base {
+			return true
+		}
+	}
+	return false
+}
+
+// sortedBases returns a sorted copy of the tuples.
+func sortedBases(bases []uint64) []uint64 {
+	bases = append([]uint64(nil), bases...)
+	sort.Slice(bases, func(i, j int) bool { return bases[i] < bases[j] })
+	return bases
+}
+
+// cutPrereleases returns the versions of the intervals except for the
+// pre-releases of the tuples.
+func cutPrereleases(ivs []Interval, bases []uint64) []Interval {
+	if len(bases) == 0 {
+		return ivs
+	}
+	pres := make([]Interval, 0, len(bases))
+	for _, base := range sortedBases(bases) {
+		pres = append(pres, Interval{&Version{base: base, pre: "0"}, &Version{base: base}})
+	}
+	return intersectIntervals(ivs, complementIntervals(pres))
+}
+
+// rangeTuples returns the pre-release tuples the guards of the ranges refer
+// to, see intervalGuards.
+func rangeTuples(rs []Interval, bases []uint64) []uint64 {
+	var refs []uint64
+	for _, r := range rs {
+		for _, g := range intervalGuards(r, bases) {
+			if len(g.ver.pre) > 0 && !containsBase(refs, g.ver.base) {
+				refs = append(refs, g.ver.base)
+			}
+		}
+	}
+	return refs
+}
+
+// sameTupleRanges adapts the intervals of a constraint matching pre-release
+// versions of the tuples bases only (PrereleaseSameTuple) to a constraint
+// built of them with intervalsConstraint. The pre-releases of a tuple not in
+// bases are cut out if its guards would refer to the tuple, and so are the
+// intervals matching such pre-releases only. An interval matching the
+// pre-releases of a tuple X in bases its guards do not refer to is split at
+// `X-0`: `^1.0 || 1.2.3-rc.1` turns into `>=1.0.0, <1.2.3-0 || >=1.2.3-0,
+// <2.0.0`. Returns the ranges to build the constraint of and the intervals
+// they match.
+func sameTupleRanges(ivs []Interval, bases []uint64) ([]Interval, []Interval) {
+	bases = sortedBases(bases)
+	var cut []uint64
+	for _, base := range rangeTuples(ivs, bases) {
+		if !containsBase(bases, base) {
+			cut = append(cut, base)
+		}
+	}
+	ivs = cutPrereleases(ivs, cut)
+	kept := make([]Interval, 0, len(ivs))
+	for _, iv := range ivs {
+		rel := iv.Lo
+		if len(rel.pre) > 0 {
+			rel = &Version{base: rel.base}
+		}
+		// Without a release the interval is within the pre-releases of a tuple
+		if rel.Less(iv.Hi) || containsBase(bases, iv.Lo.base) {
+			kept = append(kept, iv)
+		}
+	}
+	ivs = kept
+
+	refs := rangeTuples(ivs, bases)
+	rs := make([]Interval, 0, len(ivs))
+	for _, iv := range ivs {
+		for _, base := range bases {
+			at := &Version{base: base, pre: "0"}
+			if !containsBase(refs, base) && iv.Lo.Less(at) && at.Less(iv.Hi) {
+				rs = append(rs, Interval{iv.Lo, at})
+				iv.Lo = at
+			}
+		}
+		rs = append(rs, iv)
+	}
+	return rs, ivs
+}
+
+// sugarInterval renders the interval with the `^` or `~` operator if either
+// matches exactly the versions of the interval, or returns an empty string.
+func sugarInterval(iv Interval) string {
+	lo := iv.Lo
+	switch {
+	case lo.Equal(verZero) && iv.Hi.isInf():
+		return "*"
+	case lo.Less(verZero) || iv.Hi.isInf() || pred(lo) != nil:
+		return ""
+	}
+	var caret *Version
+	switch {
+	case lo.Major() > 0:
+		caret = lo.NextMajor()
+	case lo.Minor() > 0:
+		caret = lo.NextMinor()
+	default:
+		caret = lo.NextPatch()
+	}
+	switch {
+	case caret.Equal(iv.Hi):
+		return "^" + lo.String()
+	case lo.NextMinor().Equal(iv.Hi) && !lo.Equal(verZero):
+		// `~0.0.0` matches any version
+		return "~" + lo.String()
+	}
+	return ""
+}
+
+// intervalsConstraint builds a constraint tree matching the versions of the
+// intervals, see intervalGuards for bases. No intervals make a constraint
+// matching no version.
+func intervalsConstraint(ivs []Interval, bases []uint64) *Constraint {
+	if len(ivs) == 0 {
+		return &Constraint{left: NewGuard(verMin, GuardLessThan), right: (*Guard)(nil)}
+	}
+	cs := make([]*Constraint, 0, len(ivs))
+	for _, iv := range ivs {
+		gs := intervalGuards(iv, bases)
+		c := &Constraint{left: gs[0], right: (*Guard)(nil)}
+		if len(gs) > 1 {
+			c = &Constraint{left: gs[0], right: gs[1], un: ConstraintUnionAnd}
+		}
+		cs = append(cs, c)
+	}
+	c := compact(cs, ConstraintUnionOr)
+	c.ivs, c.compiled = ivs, true
+	return c
+}
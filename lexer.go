@@ -18,10 +18,19 @@ func isDash(r byte) bool {
 	return r == '-'
 }
 
+func isPlus(r byte) bool {
+	return r == '+'
+}
+
 func isStar(r byte) bool {
 	return r == '*' || r == 'x' || r == 'X'
 }
 
+// isSpace tells whether r is ASCII whitespace separating comparators.
+func isSpace(r byte) bool {
+	return r == ' ' || r == '\t' || r == '\n' || r == '\v' || r == '\f' || r == '\r'
+}
+
 func isOpChar(r byte) bool {
 	return r == '=' || r == '<' || r == '>' || r == '^' || r == '!' || r == '~'
 }
@@ -29,7 +38,7 @@ func isOpChar(r byte) bool {
 func skipTrailing(s string, i int) int {
 	j := i
 	for j < len(s) {
-		if s[j] == ' ' || s[j] == 'v' {
+		if isSpace(s[j]) || s[j] == 'v' {
 			j++
 			continue
 		}
@@ -38,6 +47,16 @@ func skipTrailing(s string, i int) int {
 	return j
 }
 
+func skipSpaces(s string, i int) int {
+	for i < len(s) && isSpace(s[i]) {
+		i++
+	}
+	return i
+}
+
+// readNum reads a decimal number starting at position i. The returned number
+// is -1 if it does not fit in an int, the returned position always points
+// right after the last digit.
 func readNum(s string, i int) (int, int) {
 	j, maxj := i, len(s)
 	for j < maxj && isNum(s[j]) {
@@ -45,19 +64,50 @@ func readNum(s string, i int) (int, int) {
 	}
 	num, err := strconv.Atoi(s[i:j])
 	if err != nil {
-		return -1, -1
+		return -1, j
 	}
 	return num, j
 }
 
 func readStr(s string, i int) (string, int) {
 	j, maxj := i, len(s)
-	for j < maxj && (isAlpha(s[j]) || isNum(s[j]) || isDot(s[j])) {
+	for j < maxj && (isAlpha(s[j]) || isNum(s[j]) || isDot(s[j]) || isDash(s[j])) {
 		j++
 	}
 	return s[i:j], j
 }
 
+// readSuffix reads an optional pre-release tag prefixed with a dash and
+// optional build metadata prefixed with a plus sign. The returned position is
+// equal to i if there is neither of them.
+func readSuffix(s string, i int) (string, string, int) {
+	var pre, meta string
+	if i < len(s) && isDash(s[i]) {
+		pre, i = readStr(s, i+1)
+	}
+	if i < len(s) && isPlus(s[i]) {
+		meta, i = readStr(s, i+1)
+	}
+	return pre, meta, i
+}
+
+func isOpStr(s string) bool {
+	_, j := readOpStr(s, 0)
+	return j == len(s)
+}
+
+// readField skips whitespace starting at position i and reads a run of
+// non-whitespace characters. Returns the start and the end positions of the
+// run.
+func readField(s string, i int) (int, int) {
+	i = skipSpaces(s, i)
+	j := i
+	for j < len(s) && !isSpace(s[j]) {
+		j++
+	}
+	return i, j
+}
+
 func readOpStr(s string, i int) (string, int) {
 	j, maxj := i, len(s)
 	for j < maxj && isOpChar(s[j]) {
@@ -65,3 +115,40 @@ func readOpStr(s string, i int) (string, int) {
 	}
 	return s[i:j], j
 }
+
+func isNumStr(s string) bool {
+	if len(s) == 0 {
+		return false
+	}
+	for i := 0; i < len(s); i++ {
+		if !isNum(s[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+// hasLeadingZero tells whether a number starting at position i has a
+// redundant leading zero.
+func hasLeadingZero(s string, i int) bool {
+	return s[i] == '0' && i+1 < len(s) && isNum(s[i+1])
+}
+
+func trimZeros(s string) string {
+	i := 0
+	for i < len(s)-1 && s[i] == '0' {
+		i++
+	}
+	return s[i:]
+}
+
+// readIdent reads a single dot-separated identifier starting at position i.
+// Returns the identifier and the position of the terminating dot (or the
+// length of the string).
+func readIdent(s string, i int) (string, int) {
+	j := i
+	for j < len(s) && !isDot(s[j]) {
+		j++
+	}
+	return s[i:j], j
+}
@@ -0,0 +1,165 @@
+package semver
+
+import (
+	"encoding/binary"
+	"fmt"
+	"strings"
+)
+
+// Packed is an encoding of a version for storage in 2 database columns, an
+// integer and a binary one, ordered the same way as the versions: sorting
+// by Base and then by Pre compared byte by byte (the order of BYTEA in
+// Postgres and BLOB in SQLite) agrees with Version.Less. Build metadata is
+// not encoded.
+//
+// Pre is empty for no version, a single 0xFF byte for a release version
+// and the sequence of pre-release identifiers ended with a 0x00 byte
+// otherwise. A numeric identifier is encoded as 0x01, the number of its
+// digits and the digits without leading zeros, an alphanumeric one as 0x02
+// followed by the identifier and 0x00. The number of digits takes a byte if
+// it is below 255 and 0xFF followed by the number as 8 big-endian bytes
+// otherwise.
+type Packed struct {
+	Base int64
+	Pre  []byte
+}
+
+const (
+	preKeyEnd     = 0x00
+	preKeyNum     = 0x01
+	preKeyAlnum   = 0x02
+	preKeyRelease = 0xFF
+	// preKeyLongNum marks the 8 byte length of a numeric identifier
+	preKeyLongNum = 0xFF
+)
+
+// Packed returns the packed encoding of the version.
+func (v Version) Packed() Packed {
+	return Packed{
+		Base: int64(v.base),
+		Pre:  appendPreKey(make([]byte, 0, len(v.pre)+4), v.pre),
+	}
+}
+
+// Version decodes the packed version.
+func (p Packed) Version() (*Version, error) {
+	if p.Base < 0 || uint64(p.Base) >= baseInf {
+		return nil, fmt.Errorf("%w: packed base out of range: %#x", ErrInvalidSemVer, p.Base)
+	}
+	pre, n, err := readPreKey(p.Pre)
+	if err != nil {
+		return nil, err
+	}
+	if n != len(p.Pre) {
+		return nil, fmt.Errorf("%w: trailing bytes in packed pre-release", ErrInvalidSemVer)
+	}
+	return &Version{base: uint64(p.Base), pre: pre}, nil
+}
+
+// MarshalBinary implements encoding.BinaryMarshaler. The encoding is ordered
+// by precedence when compared byte by byte, so it suits the keys of ordered
+// key-value stores: it consists of the packed numbers as 8 big-endian bytes,
+// the pre-release tag encoded the way Packed does and the build metadata.
+// Versions of equal precedence are ordered by their build metadata.
+func (v Version) MarshalBinary() ([]byte, error) {
+	b := make([]byte, 8, 8+len(v.pre)+4+len(v.meta))
+	binary.BigEndian.PutUint64(b, v.base)
+	b = appendPreKey(b, v.pre)
+	return append(b, v.meta...), nil
+}
+
+// UnmarshalBinary implements encoding.BinaryUnmarshaler.
+func (v *Version) UnmarshalBinary(b []byte) error {
+	if len(b) < 8 {
+		return fmt.Errorf("%w: binary version too short: %d bytes", ErrInvalidSemVer, len(b))
+	}
+	base := binary.BigEndian.Uint64(b)
+	if base >= baseInf {
+		return fmt.Errorf("%w: packed base out of range: %#x", ErrInvalidSemVer, base)
+	}
+	pre, n, err := readPreKey(b[8:])
+	if err != nil {
+		return err
+	}
+	*v = Version{base: base, pre: pre, meta: string(b[8+n:])}
+	return nil
+}
+
+// appendPreKey appends the order preserving encoding of a pre-release tag,
+// see Packed.
+func appendPreKey(b []byte, pre string) []byte {
+	if len(pre) == 0 {
+		return append(b, preKeyRelease)
+	}
+	for i := 0; i < len(pre); {
+		id, j := readIdent(pre, i)
+		if isNumStr(id) {
+			id = trimZeros(id)
+			b = append(b, preKeyNum)
+			if len(id) < preKeyLongNum {
+				b = append(b, byte(len(id)))
+			} else {
+				var n [8]byte
+				binary.BigEndian.PutUint64(n[:], uint64(len(id)))
+				b = append(append(b, preKeyLongNum), n[:]...)
+			}
+			b = append(b, id...)
+		} else {
+			b = append(b, preKeyAlnum)
+			b = append(b, id...)
+			b = append(b, preKeyEnd)
+		}
+		i = j + 1
+	}
+	return append(b, preKeyEnd)
+}
+
+// readPreKey decodes a pre-release tag encoded with appendPreKey. Returns the
+// tag and the length of its encoding.
+func readPreKey(b []byte) (string, int, error) {
+	if len(b) > 0 && b[0] == preKeyRelease {
+		return "", 1, nil
+	}
+	var sb strings.Builder
+	for i := 0; i < len(b); {
+		var id []byte
+		switch b[i] {
+		case preKeyEnd:
+			return sb.String(), i + 1, nil
+		case preKeyNum:
+			if i+1 >= len(b) {
+				goto Err
+			}
+			j, n := i+2, uint64(b[i+1])
+			if n == preKeyLongNum {
+				if j+8 > len(b) {
+					goto Err
+				}
+				j, n = j+8, binary.BigEndian.Uint64(b[j:])
+			}
+			if n > uint64(len(b)-j) {
+				goto Err
+			}
+			id = b[j : j+int(n)]
+			i = j + len(id)
+		case preKeyAlnum:
+			j := i + 1
+			for j < len(b) && b[j] != preKeyEnd {
+				j++
+			}
+			if j == len(b) {
+				goto Err
+			}
+			id = b[i+1 : j]
+			i = j + 1
+		default:
+			goto Err
+		}
+		if sb.Len() > 0 {
+			sb.WriteByte('.')
+		}
+		sb.Write(id)
+	}
+Err:
+	return "", 0, fmt.Errorf("%w: malformed packed pre-release", ErrInvalidSemVer)
+}
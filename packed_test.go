@@ -0,0 +1,147 @@
+package semver
+
+import (
+	"bytes"
+	"errors"
+	"math/rand"
+	"strings"
+	"testing"
+)
+
+func comparePacked(p1, p2 Packed) int {
+	switch {
+	case p1.Base < p2.Base:
+		return -1
+	case p1.Base > p2.Base:
+		return 1
+	}
+	return bytes.Compare(p1.Pre, p2.Pre)
+}
+
+func TestPackedOrder(t *testing.T) {
+	r := rand.New(rand.NewSource(9))
+	for n := 0; n < 20000; n++ {
+		s1, s2 := randVersion(r), randVersion(r)
+		v1, v2 := newVersionUnsafe(s1), newVersionUnsafe(s2)
+		p1, p2 := v1.Packed(), v2.Packed()
+		if c, ref := comparePacked(p1, p2), refCompare(s1, s2); c != ref {
+			t.Fatalf("unexpected order of packed %s and %s: got: %d, want: %d", s1, s2, c, ref)
+		}
+		dv, err := p1.Version()
+		if err != nil {
+			t.Fatalf("unexpected error decoding %s: %v", s1, err)
+		}
+		if !dv.Equal(v1) || dv.Pre() != v1.Pre() {
+			t.Fatalf("unexpected decoded version: got: %s, want: %s", dv, v1)
+		}
+	}
+}
+
+func TestPackedEncoding(t *testing.T) {
+	tests := []struct {
+		Version string
+		Expect  []byte
+	}{
+		{Version: "1.2.3", Expect: []byte{0xFF}},
+		{Version: "1.2.3-0", Expect: []byte{0x01, 1, '0', 0x00}},
+		{Version: "1.2.3-rc.10", Expect: []byte{0x02, 'r', 'c', 0x00, 0x01, 2, '1', '0', 0x00}},
+		{Version: "1.2.3-x-1.007", Expect: []byte{0x02, 'x', '-', '1', 0x00, 0x01, 1, '7', 0x00}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.Version, func(t *testing.T) {
+			p := newVersionUnsafe(tt.Version).Packed()
+			if p.Base != 1<<majorShift|2<<minorShift|3 {
+				t.Fatalf("unexpected packed base: got: %#x", p.Base)
+			}
+			if !bytes.Equal(p.Pre, tt.Expect) {
+				t.Fatalf("unexpected packed pre-release: got: %q, want: %q", p.Pre, tt.Expect)
+			}
+		})
+	}
+
+	// Numeric identifiers of 255 digits and longer have 8 byte lengths
+	var prev Packed
+	for i, n := range []int{1, 254, 255, 256, 300} {
+		s := "1.2.3-rc." + strings.Repeat("9", n)
+		p := newVersionUnsafe(s).Packed()
+		if i > 0 && comparePacked(prev, p) >= 0 {
+			t.Fatalf("unexpected order of packed %s: got: %q, previous: %q", s, p.Pre, prev.Pre)
+		}
+		if dv, err := p.Version(); err != nil || dv.String() != s {
+			t.Fatalf("unexpected decoded version: got: %v, want: %s, error: %v", dv, s, err)
+		}
+		prev = p
+	}
+
+	for _, p := range []Packed{
+		{Base: -1, Pre: []byte{0xFF}},
+		{Base: 1 << 21, Pre: nil},
+		{Base: 1 << 21, Pre: []byte{0xFF, 0x00}},
+		{Base: 1 << 21, Pre: []byte{0x01, 3, '1', 0x00}},
+		{Base: 1 << 21, Pre: []byte{0x02, 'r', 'c'}},
+		{Base: 1 << 21, Pre: []byte{0x03, 0x00}},
+		{Base: 1 << 21, Pre: []byte{0x01, 0xFF, 0, 0, 0, 0, 0, 0, 1}},
+		{Base: 1 << 21, Pre: []byte{0x01, 0xFF, 0xFF, 0, 0, 0, 0, 0, 0, 0, '1', 0x00}},
+	} {
+		if _, err := p.Version(); !errors.Is(err, ErrInvalidSemVer) {
+			t.Fatalf("unexpected error decoding %#v: got: %v, want: %v", p, err, ErrInvalidSemVer)
+		}
+	}
+}
+
+func TestVersionBinary(t *testing.T) {
+	r := rand.New(rand.NewSource(11))
+	for n := 0; n < 20000; n++ {
+		s1, s2 := randVersion(r), randVersion(r)
+		v1, v2 := newVersionUnsafe(s1), newVersionUnsafe(s2)
+		b1, _ := v1.MarshalBinary()
+		b2, _ := v2.MarshalBinary()
+		if c, ref := bytes.Compare(b1, b2), refCompare(s1, s2); ref != 0 && c != ref {
+			t.Fatalf("unexpected order of binary %s and %s: got: %d, want: %d", s1, s2, c, ref)
+		}
+		var dv Version
+		if err := dv.UnmarshalBinary(b1); err != nil {
+			t.Fatalf("unexpected error decoding %s: %v", s1, err)
+		}
+		if s := dv.String(); s != v1.String() {
+			t.Fatalf("unexpected decoded version: got: %s, want: %s", s, v1)
+		}
+	}
+
+	v := newVersionUnsafe("1.2.3-rc.1+build.5")
+	expect := []byte{0, 0, 4, 0, 0, 0x40, 0, 3, 0x02, 'r', 'c', 0x00, 0x01, 1, '1', 0x00, 'b', 'u', 'i', 'l', 'd', '.', '5'}
+	if b, _ := v.MarshalBinary(); !bytes.Equal(b, expect) {
+		t.Fatalf("unexpected binary version: got: %q, want: %q", b, expect)
+	}
+
+	var prev []byte
+	for i, n := range []int{200, 255, 256, 1000} {
+		s := "1.2.3-" + strings.Repeat("1", n) + "+build"
+		b, err := newVersionUnsafe(s).MarshalBinary()
+		if err != nil {
+			t.Fatalf("unexpected error encoding %s: %v", s, err)
+		}
+		if i > 0 && bytes.Compare(prev, b) >= 0 {
+			t.Fatalf("unexpected order of binary %s: got: %q, previous: %q", s, b, prev)
+		}
+		var dv Version
+		if err := dv.UnmarshalBinary(b); err != nil || dv.String() != s {
+			t.Fatalf("unexpected decoded version: got: %s, want: %s, error: %v", &dv, s, err)
+		}
+		prev = b
+	}
+
+	for _, b := range [][]byte{
+		nil,
+		{0, 0, 0, 0, 0, 0, 0},
+		{0x80, 0, 0, 0, 0, 0, 0, 0, 0xFF},
+		{0, 0, 0, 0, 0, 0, 0, 1},
+		{0, 0, 0, 0, 0, 0, 0, 1, 0x02, 'r', 'c'},
+	} {
+		var v Version
+		if err := v.UnmarshalBinary(b); !errors.Is(err, ErrInvalidSemVer) {
+			t.Fatalf("unexpected error decoding %q: got: %v, want: %v", b, err, ErrInvalidSemVer)
+		}
+	}
+}
@@ -0,0 +1,94 @@
+package semver
+
+import "strings"
+
+// SQL compiles the constraint into a parameterized predicate over a version
+// stored in the Packed encoding: baseCol and preCol are the names of the
+// columns holding Packed.Base and Packed.Pre, they are inserted into the
+// predicate as is. The predicate uses `?` placeholders, the returned
+// arguments are int64 and []byte values. The pre-release policy of the
+// constraint is a part of the predicate.
+//
+//	>=1.2.0, <2.0.0 turns into
+//	((base > ? OR (base = ? AND pre >= ?)) AND (base < ? OR (base = ? AND pre < ?))) AND pre = ?
+func (c *Constraint) SQL(baseCol, preCol string) (string, []interface{}) {
+	ivs := c.intervals()
+	if len(ivs) == 0 {
+		return "1 = 0", nil
+	}
+
+	var ranges []string
+	var args []interface{}
+	release := appendPreKey(nil, "")
+	for _, iv := range ivs {
+		var bounds []string
+		if !iv.Lo.Equal(verMin) {
+			bounds = append(bounds, "("+baseCol+" > ? OR ("+baseCol+" = ? AND "+preCol+" >= ?))")
+			base := int64(iv.Lo.base)
+			args = append(args, base, base, appendPreKey(nil, iv.Lo.pre))
+		}
+		if !iv.Hi.isInf() {
+			bounds = append(bounds, "("+baseCol+" < ? OR ("+baseCol+" = ? AND "+preCol+" < ?))")
+			base := int64(iv.Hi.base)
+			args = append(args, base, base, appendPreKey(nil, iv.Hi.pre))
+		}
+		if len(bounds) == 0 {
+			// The interval matches any version, so it is the only one
+			break
+		}
+		if len(bounds) > 1 {
+			ranges = append(ranges, "("+strings.Join(bounds, " AND ")+")")
+		} else {
+			ranges = append(ranges, bounds[0])
+		}
+	}
+	pred := strings.Join(ranges, " OR ")
+	if len(ranges) > 1 {
+		pred = "(" + pred + ")"
+	}
+
+	var policy string
+	switch c.policy {
+	case PrereleaseExclude:
+		policy = preCol + " = ?"
+		args = append(args, release)
+	case PrereleaseSameTuple:
+		policy = preCol + " = ?"
+		args = append(args, release)
+		if bases := c.preTuples(nil); len(bases) > 0 {
+			policy = "(" + policy + " OR " + baseCol + " IN (?" + strings.Repeat(", ?", len(bases)-1) + "))"
+			for _, base := range bases {
+				args = append(args, int64(base))
+			}
+		}
+	}
+
+	switch {
+	case pred == "" && policy == "":
+		return "1 = 1", nil
+	case pred == "":
+		return policy, args
+	case policy == "":
+		return pred, args
+	}
+	return pred + " AND " + policy, args
+}
+
+// preTuples appends the distinct major.minor.patch tuples of the pre-release
+// versions the guards of the constraint tree refer to, see hasPreTuple.
+func (c *Constraint) preTuples(bases []uint64) []uint64 {
+	for _, ch := range [...]Checker{c.left, c.right} {
+		switch n := ch.(type) {
+		case *Guard:
+			if n == nil || len(n.ver.pre) == 0 {
+				continue
+			}
+			if !containsBase(bases, n.ver.base) {
+				bases = append(bases, n.ver.base)
+			}
+		case *Constraint:
+			bases = n.preTuples(bases)
+		}
+	}
+	return bases
+}
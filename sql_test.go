@@ -0,0 +1,190 @@
+package semver
+
+import (
+	"bytes"
+	"math/rand"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// sqlEval interprets the predicates generated by Constraint.SQL for a single
+// row with the columns `base` and `pre`.
+type sqlEval struct {
+	toks []string
+	args []interface{}
+	row  Packed
+}
+
+func evalSQL(t *testing.T, pred string, args []interface{}, row Packed) bool {
+	r := strings.NewReplacer("(", " ( ", ")", " ) ", ",", " , ")
+	e := &sqlEval{toks: strings.Fields(r.Replace(pred)), args: args, row: row}
+	res := e.or(t)
+	if len(e.toks) > 0 || len(e.args) > 0 {
+		t.Fatalf("unexpected trailing tokens %q or arguments %v of %q", e.toks, e.args, pred)
+	}
+	return res
+}
+
+func (e *sqlEval) next(t *testing.T) string {
+	if len(e.toks) == 0 {
+		t.Fatalf("unexpected end of predicate")
+	}
+	tok := e.toks[0]
+	e.toks = e.toks[1:]
+	return tok
+}
+
+func (e *sqlEval) peek() string {
+	if len(e.toks) == 0 {
+		return ""
+	}
+	return e.toks[0]
+}
+
+func (e *sqlEval) or(t *testing.T) bool {
+	res := e.and(t)
+	for e.peek() == "OR" {
+		e.next(t)
+		res = e.and(t) || res
+	}
+	return res
+}
+
+func (e *sqlEval) and(t *testing.T) bool {
+	res := e.atom(t)
+	for e.peek() == "AND" {
+		e.next(t)
+		res = e.atom(t) && res
+	}
+	return res
+}
+
+func (e *sqlEval) atom(t *testing.T) bool {
+	if e.peek() == "(" {
+		e.next(t)
+		res := e.or(t)
+		if tok := e.next(t); tok != ")" {
+			t.Fatalf("unexpected token: got: %q, want: %q", tok, ")")
+		}
+		return res
+	}
+	v1 := e.operand(t)
+	switch op := e.next(t); op {
+	case "IN":
+		res := false
+		for tok := e.next(t); tok != ")"; tok = e.next(t) {
+			if tok == "(" || tok == "," {
+				res = e.compare(t, v1, e.operand(t)) == 0 || res
+			}
+		}
+		return res
+	case "=":
+		return e.compare(t, v1, e.operand(t)) == 0
+	case "<":
+		return e.compare(t, v1, e.operand(t)) < 0
+	case "<=":
+		return e.compare(t, v1, e.operand(t)) <= 0
+	case ">":
+		return e.compare(t, v1, e.operand(t)) > 0
+	case ">=":
+		return e.compare(t, v1, e.operand(t)) >= 0
+	default:
+		t.Fatalf("unexpected operator %q", op)
+	}
+	return false
+}
+
+func (e *sqlEval) operand(t *testing.T) interface{} {
+	switch tok := e.next(t); tok {
+	case "base":
+		return e.row.Base
+	case "pre":
+		return e.row.Pre
+	case "?":
+		if len(e.args) == 0 {
+			t.Fatalf("missing predicate argument")
+		}
+		arg := e.args[0]
+		e.args = e.args[1:]
+		return arg
+	default:
+		n, err := strconv.ParseInt(tok, 10, 64)
+		if err != nil {
+			t.Fatalf("unexpected operand %q", tok)
+		}
+		return n
+	}
+}
+
+func (e *sqlEval) compare(t *testing.T, v1, v2 interface{}) int {
+	switch v1 := v1.(type) {
+	case int64:
+		if v2, ok := v2.(int64); ok {
+			switch {
+			case v1 < v2:
+				return -1
+			case v1 > v2:
+				return 1
+			}
+			return 0
+		}
+	case []byte:
+		if v2, ok := v2.([]byte); ok {
+			return bytes.Compare(v1, v2)
+		}
+	}
+	t.Fatalf("incomparable operands %v and %v", v1, v2)
+	return 0
+}
+
+func TestConstraintSQL(t *testing.T) {
+	tests := []struct {
+		Constraint string
+		Policy     PrereleasePolicy
+		Expect     string
+	}{
+		{
+			Constraint: ">=1.2.0, <2.0.0",
+			Policy:     PrereleaseInclude,
+			Expect:     "((base > ? OR (base = ? AND pre >= ?)) AND (base < ? OR (base = ? AND pre < ?)))",
+		},
+		{
+			Constraint: "^1.2 || >=3.0.0-rc.1",
+			Expect:     "(((base > ? OR (base = ? AND pre >= ?)) AND (base < ? OR (base = ? AND pre < ?))) OR (base > ? OR (base = ? AND pre >= ?))) AND (pre = ? OR base IN (?))",
+		},
+		{Constraint: "<1.0.0", Policy: PrereleaseExclude, Expect: "(base < ? OR (base = ? AND pre < ?)) AND pre = ?"},
+		{Constraint: "*", Policy: PrereleaseInclude, Expect: "(base > ? OR (base = ? AND pre >= ?))"},
+		{Constraint: "<0.0.0-0 || >=0.0.0-0", Policy: PrereleaseInclude, Expect: "1 = 1"},
+		{Constraint: "<0.0.0-0 || >=0.0.0-0", Expect: "(pre = ? OR base IN (?))"},
+		{Constraint: ">=0.0.0", Policy: PrereleaseExclude, Expect: "(base > ? OR (base = ? AND pre >= ?)) AND pre = ?"},
+		{Constraint: ">2.0.0, <1.0.0", Expect: "1 = 0"},
+		{Constraint: "!=1.2.3"},
+		{Constraint: "1.2.3-alpha || 1.2.3-beta || 2.0.0-rc.1"},
+		{Constraint: ">1.2.3-alpha, <=2.1"},
+		{Constraint: "~0.1.2 || 1.x || >=2.2.0-beta.2, <2.2.1"},
+	}
+
+	r := rand.New(rand.NewSource(10))
+	for _, tt := range tests {
+		for _, policy := range []PrereleasePolicy{PrereleaseSameTuple, PrereleaseExclude, PrereleaseInclude} {
+			if tt.Expect != "" && policy != tt.Policy {
+				continue
+			}
+			c, err := NewConstraint(tt.Constraint, WithPrereleasePolicy(policy))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			pred, args := c.SQL("base", "pre")
+			if tt.Expect != "" && pred != tt.Expect {
+				t.Fatalf("unexpected predicate of %q: got: %q, want: %q", tt.Constraint, pred, tt.Expect)
+			}
+			for n := 0; n < 2000; n++ {
+				v := newVersionUnsafe(randVersion(r))
+				if got, want := evalSQL(t, pred, args, v.Packed()), c.Check(v); got != want {
+					t.Fatalf("unexpected evaluation of %q (%d) for %s: got: %t, want: %t", tt.Constraint, policy, v, got, want)
+				}
+			}
+		}
+	}
+}
@@ -1,27 +1,106 @@
 package semver
 
+import (
+	"database/sql/driver"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
 // Version represents a parsed SemVer term.
-// `base` encodes 3 10-bit numbers of a SemVer version.
+// `base` encodes 3 21-bit numbers of a SemVer version.
 // In binary format it looks like:
-// 0b00 XXXXXXXXXX XXXXXXXXXX XXXXXXXXXX
-//  |        |          |          |
-//  |        |          |          '---Patch version, 10 bits
-//  |        |          '---Minor version, 10 bits
-//  |        '---Major version, 10 bits
-//  '---Unused, 2 bits
+// 0b0 XXXXXXXXXXXXXXXXXXXXX XXXXXXXXXXXXXXXXXXXXX XXXXXXXXXXXXXXXXXXXXX
+//  |           |                     |                     |
+//  |           |                     |                     '---Patch version, 21 bits
+//  |           |                     '---Minor version, 21 bits
+//  |           '---Major version, 21 bits
+//  '---Unused, 1 bit
 //
-// Therefore a max value for every number is 1023: the last version can't exceed
-// 1023.1023.1023
+// Therefore a max value for every number is 2097151 (MaxComponent): the last
+// version can't exceed 2097151.2097151.2097151. The unused bit keeps `base`
+// non-negative when it is stored as a signed 64-bit integer and leaves room
+// for a version following the last representable one, which is used as an
+// upper bound of open ranges.
 //
 // `pre` contains the pre-release tag as a string and therefore has no upper
 // limitations.
+//
+// `meta` contains the build metadata. It is preserved as is but is never taken
+// into account when versions are compared.
 type Version struct {
-	base uint32
+	base uint64
 	pre  string
+	meta string
 }
 
+// MaxComponent is the max value of a major, minor or patch version number.
+const MaxComponent = 1<<componentBits - 1
+
+const (
+	componentBits = 21
+	minorShift    = componentBits
+	majorShift    = 2 * componentBits
+
+	patchMask uint64 = MaxComponent
+	minorMask        = patchMask << minorShift
+	majorMask        = patchMask << majorShift
+
+	// baseInf is the base of a version following the last representable one
+	baseInf uint64 = 1 << (3 * componentBits)
+)
+
+// ParseFlags is a set of relaxations of the SemVer 2.0.0 grammar a version
+// parser is allowed to apply.
+type ParseFlags uint8
+
+const (
+	// AllowPrefix permits any number of leading spaces and `v` characters:
+	// ` v1.2.3`.
+	AllowPrefix ParseFlags = 1 << iota
+	// AllowMissing permits omitting the minor and the patch numbers, which
+	// default to 0: `1` is `1.0.0`, `1.2` is `1.2.0`.
+	AllowMissing
+	// AllowLeadingZeros permits leading zeros in numbers and numeric
+	// pre-release identifiers: `01.02.03-rc.01`.
+	AllowLeadingZeros
+	// AllowEmptyIdents permits empty pre-release and build metadata
+	// identifiers: `1.2.3-rc..1`, `1.2.3-`.
+	AllowEmptyIdents
+	// AllowTrailing permits and ignores anything following a pre-release tag
+	// or build metadata: `1.2.3-rc.1 (final)`.
+	AllowTrailing
+)
+
+const (
+	// ParseStrictFlags enforce the SemVer 2.0.0 grammar exactly.
+	ParseStrictFlags ParseFlags = 0
+	// ParseLenientFlags enable all the relaxations.
+	ParseLenientFlags = AllowPrefix | AllowMissing | AllowLeadingZeros | AllowEmptyIdents | AllowTrailing
+)
+
+// NewVersion parses a version in the lenient mode, see ParseLenient.
 func NewVersion(s string) (*Version, error) {
-	base, pre, err := parseVersion(s)
+	return ParseVersion(s, ParseLenientFlags)
+}
+
+// ParseStrict parses a version that must conform to the SemVer 2.0.0 grammar
+// exactly.
+func ParseStrict(s string) (*Version, error) {
+	return ParseVersion(s, ParseStrictFlags)
+}
+
+// ParseLenient parses a version applying all the grammar relaxations, see
+// ParseLenientFlags.
+func ParseLenient(s string) (*Version, error) {
+	return ParseVersion(s, ParseLenientFlags)
+}
+
+// ParseVersion parses a version applying only the grammar relaxations
+// enabled in flags.
+func ParseVersion(s string, flags ParseFlags) (*Version, error) {
+	base, pre, meta, err := parseVersion(s, flags)
 	if err != nil {
 		return nil, err
 	}
@@ -29,74 +108,196 @@ func NewVersion(s string) (*Version, error) {
 	return &Version{
 		base: base,
 		pre:  pre,
+		meta: meta,
 	}, nil
 }
 
-func NewVersionRaw(ds []uint32, pre string) *Version {
-	var base uint32
-	for i, d := range ds {
-		if i > 3 {
-			break
+// NewVersionRaw builds a version from up to 3 numbers: major, minor and
+// patch. Missing numbers default to 0. Numbers exceeding MaxComponent are
+// reported with ErrOverflow.
+func NewVersionRaw(ds []uint32, pre string) (*Version, error) {
+	if len(ds) > 3 {
+		return nil, fmt.Errorf("%w: too many version components: %d", ErrInvalidSemVer, len(ds))
+	}
+	for _, d := range ds {
+		if d > MaxComponent {
+			return nil, fmt.Errorf("%w: %d", ErrOverflow, d)
 		}
-		base |= d << uint((2-i)*10)
+	}
+	return newVersionRaw(ds, pre), nil
+}
+
+// newVersionRaw is NewVersionRaw for numbers known to be in range.
+func newVersionRaw(ds []uint32, pre string) *Version {
+	var base uint64
+	for i, d := range ds {
+		base |= uint64(d) << uint((2-i)*componentBits)
 	}
 	return &Version{base: base, pre: pre}
 }
 
 func (v Version) Major() uint32 {
-	return (v.base >> 20) & 0x3FF
+	return uint32((v.base >> majorShift) & patchMask)
 }
 
 func (v Version) Minor() uint32 {
-	return (v.base >> 10) & 0x3FF
+	return uint32((v.base >> minorShift) & patchMask)
 }
 
 func (v Version) Patch() uint32 {
-	return v.base & 0x3FF
+	return uint32(v.base & patchMask)
 }
 
 func (v Version) Pre() string {
 	return v.pre
 }
 
+func (v Version) Metadata() string {
+	return v.meta
+}
+
+// isInf tells whether the version follows all the representable ones.
+func (v Version) isInf() bool {
+	return v.base >= baseInf
+}
+
+// String returns the canonical form of the version: `1.2.3-rc.1+meta`.
+func (v Version) String() string {
+	return string(v.appendTo(make([]byte, 0, 16+len(v.pre)+len(v.meta))))
+}
+
+// Prefixed returns the canonical form of the version prefixed with a `v`:
+// `v1.2.3-rc.1+meta`.
+func (v Version) Prefixed() string {
+	return string(v.appendTo(append(make([]byte, 0, 17+len(v.pre)+len(v.meta)), 'v')))
+}
+
+func (v Version) appendTo(b []byte) []byte {
+	b = strconv.AppendUint(b, uint64(v.Major()), 10)
+	b = append(b, '.')
+	b = strconv.AppendUint(b, uint64(v.Minor()), 10)
+	b = append(b, '.')
+	b = strconv.AppendUint(b, uint64(v.Patch()), 10)
+	if len(v.pre) > 0 {
+		b = append(b, '-')
+		b = append(b, v.pre...)
+	}
+	if len(v.meta) > 0 {
+		b = append(b, '+')
+		b = append(b, v.meta...)
+	}
+	return b
+}
+
+// Format implements fmt.Formatter. %s and %v print the canonical form, %q
+// prints it quoted, %+v appends the packed base number and %#v prints the
+// Go syntax representation of the struct.
+func (v Version) Format(f fmt.State, verb rune) {
+	var s string
+	switch verb {
+	case 's', 'v':
+		switch {
+		case verb == 'v' && f.Flag('#'):
+			s = fmt.Sprintf("semver.Version{base:%#016x, pre:%q, meta:%q}", v.base, v.pre, v.meta)
+		case verb == 'v' && f.Flag('+'):
+			s = fmt.Sprintf("%s (base: %#016x)", v.String(), v.base)
+		default:
+			s = v.String()
+		}
+	case 'q':
+		s = strconv.Quote(v.String())
+	default:
+		fmt.Fprintf(f, "%%!%c(semver.Version=%s)", verb, v.String())
+		return
+	}
+	if w, ok := f.Width(); ok && w > len(s) {
+		if f.Flag('-') {
+			s += strings.Repeat(" ", w-len(s))
+		} else {
+			s = strings.Repeat(" ", w-len(s)) + s
+		}
+	}
+	io.WriteString(f, s)
+}
+
+// MarshalText implements encoding.TextMarshaler, the version is encoded in
+// the canonical form.
+func (v Version) MarshalText() ([]byte, error) {
+	return v.appendTo(make([]byte, 0, 16+len(v.pre)+len(v.meta))), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler, the version is decoded
+// with NewVersion.
+func (v *Version) UnmarshalText(b []byte) error {
+	nv, err := NewVersion(string(b))
+	if err != nil {
+		return err
+	}
+	*v = *nv
+	return nil
+}
+
+// Scan implements sql.Scanner for versions stored in the text form.
+func (v *Version) Scan(src interface{}) error {
+	switch s := src.(type) {
+	case string:
+		return v.UnmarshalText([]byte(s))
+	case []byte:
+		return v.UnmarshalText(s)
+	}
+	return fmt.Errorf("%w: can not scan %T", ErrInvalidSemVer, src)
+}
+
+// Value implements driver.Valuer, the version is stored in the canonical
+// form. See Packed for an encoding ordered by precedence.
+func (v Version) Value() (driver.Value, error) {
+	return v.String(), nil
+}
+
+// Incrementing the max number carries over to the next significant one, so
+// the next major of 2097151.0.0 is a version following all the representable
+// ones.
 func (v Version) NextMajor() *Version {
 	return &Version{
-		base: ((v.Major() + 1) & 0x3FF) << 20,
+		base: (uint64(v.Major()) + 1) << majorShift,
 	}
 }
 
 func (v Version) PreMajor() *Version {
 	return &Version{
-		base: ((v.Major() - 1) & 0x3FF) << 20,
+		base: (uint64(v.Major()-1) & patchMask) << majorShift,
 	}
 }
 
 func (v Version) NextMinor() *Version {
 	return &Version{
-		base: (v.base & 0x3FF00000) | (((v.Minor() + 1) & 0x3FF) << 10),
+		base: (v.base & majorMask) + ((uint64(v.Minor()) + 1) << minorShift),
 	}
 }
 
 func (v Version) PrevMinor() *Version {
 	return &Version{
-		base: (v.base & 0x3FF00000) | (((v.Minor() - 1) & 0x3FF) << 10),
+		base: (v.base & majorMask) | ((uint64(v.Minor()-1) & patchMask) << minorShift),
 	}
 }
 
 func (v Version) NextPatch() *Version {
 	return &Version{
-		base: (v.base & 0x3FFFFC00) | ((v.Patch() + 1) & 0x3FF),
+		base: (v.base & (majorMask | minorMask)) + uint64(v.Patch()) + 1,
 	}
 }
 
 func (v Version) PrevPatch() *Version {
 	return &Version{
-		base: (v.base & 0x3FFFFC00) | ((v.Patch() - 1) & 0x3FF),
+		base: (v.base & (majorMask | minorMask)) | (uint64(v.Patch()-1) & patchMask),
 	}
 }
 
 func (v1 Version) Equal(v2 *Version) bool {
-	return v1.base == v2.base && v1.pre == v2.pre
+	if v1.base != v2.base {
+		return false
+	}
+	return v1.pre == v2.pre || comparePre(v1.pre, v2.pre) == 0
 }
 
 func (v1 Version) Less(v2 *Version) bool {
@@ -105,7 +306,7 @@ func (v1 Version) Less(v2 *Version) bool {
 	} else if v1.base == v2.base {
 		lv1, lv2 := len(v1.pre), len(v2.pre)
 		if lv1 != 0 && lv2 != 0 {
-			return v1.pre < v2.pre
+			return comparePre(v1.pre, v2.pre) < 0
 		}
 		return lv1 > 0
 	}
@@ -1,6 +1,10 @@
 package semver
 
 import (
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
 	"reflect"
 	"testing"
 )
@@ -26,7 +30,7 @@ func TestConstraint(t *testing.T) {
 		{
 			Name: "1.24.32-alpha.0",
 			Version: &Version{
-				base: (1 << 20) | (24 << 10) | 32,
+				base: (1 << majorShift) | (24 << minorShift) | 32,
 				pre:  "alpha.0",
 			},
 			Expect: true,
@@ -34,7 +38,7 @@ func TestConstraint(t *testing.T) {
 		{
 			Name: "1.24.32-alpha.1",
 			Version: &Version{
-				base: (1 << 20) | (24 << 10) | 32,
+				base: (1 << majorShift) | (24 << minorShift) | 32,
 				pre:  "alpha.1",
 			},
 			Expect: true,
@@ -42,14 +46,14 @@ func TestConstraint(t *testing.T) {
 		{
 			Name: "1.24.32",
 			Version: &Version{
-				base: (1 << 20) | (24 << 10) | 32,
+				base: (1 << majorShift) | (24 << minorShift) | 32,
 			},
 			Expect: true,
 		},
 		{
 			Name: "1.24.31-alpha.0",
 			Version: &Version{
-				base: (1 << 20) | (24 << 10) | 31,
+				base: (1 << majorShift) | (24 << minorShift) | 31,
 				pre:  "alpha.0",
 			},
 			Expect: false,
@@ -57,7 +61,7 @@ func TestConstraint(t *testing.T) {
 		{
 			Name: "1.23.32-alpha.0",
 			Version: &Version{
-				base: (1 << 20) | (23 << 10) | 32,
+				base: (1 << majorShift) | (23 << minorShift) | 32,
 				pre:  "alpha.0",
 			},
 			Expect: false,
@@ -65,7 +69,7 @@ func TestConstraint(t *testing.T) {
 		{
 			Name: "0.24.32-alpha.0",
 			Version: &Version{
-				base: (0 << 20) | (24 << 10) | 32,
+				base: (0 << majorShift) | (24 << minorShift) | 32,
 				pre:  "alpha.0",
 			},
 			Expect: false,
@@ -73,7 +77,7 @@ func TestConstraint(t *testing.T) {
 		{
 			Name: "2.0.0",
 			Version: &Version{
-				base: 2 << 20,
+				base: 2 << majorShift,
 			},
 			Expect: false,
 		},
@@ -97,7 +101,7 @@ func TestNewVersion(t *testing.T) {
 		{
 			Input: "1.2.3-beta.2",
 			ExpectVer: Version{
-				base: (1 << 20) | (2 << 10) | (3),
+				base: (1 << majorShift) | (2 << minorShift) | (3),
 				pre:  "beta.2",
 			},
 		},
@@ -114,13 +118,54 @@ func TestNewVersion(t *testing.T) {
 		{
 			Input: "0.1.0",
 			ExpectVer: Version{
-				base: (1 << 10),
+				base: (1 << minorShift),
 			},
 		},
 		{
 			Input: "1.0.0",
 			ExpectVer: Version{
-				base: (1 << 20),
+				base: (1 << majorShift),
+			},
+		},
+		{
+			Input: "2024.3.1",
+			ExpectVer: Version{
+				base: (2024 << majorShift) | (3 << minorShift) | (1),
+			},
+		},
+		{
+			Input: "120.0.6099",
+			ExpectVer: Version{
+				base: (120 << majorShift) | (6099),
+			},
+		},
+		{
+			Input: "2097151.2097151.2097151",
+			ExpectVer: Version{
+				base: baseInf - 1,
+			},
+		},
+		{
+			Input: "1.2.3+sha.abc123",
+			ExpectVer: Version{
+				base: (1 << majorShift) | (2 << minorShift) | (3),
+				meta: "sha.abc123",
+			},
+		},
+		{
+			Input: "1.2.3-rc.1+build.5",
+			ExpectVer: Version{
+				base: (1 << majorShift) | (2 << minorShift) | (3),
+				pre:  "rc.1",
+				meta: "build.5",
+			},
+		},
+		{
+			Input: "1.0.0-x-y.1+git-8c1a",
+			ExpectVer: Version{
+				base: (1 << majorShift),
+				pre:  "x-y.1",
+				meta: "git-8c1a",
 			},
 		},
 	}
@@ -141,6 +186,372 @@ func TestNewVersion(t *testing.T) {
 	}
 }
 
+func TestParseVersionFlags(t *testing.T) {
+	tests := []struct {
+		Input  string
+		Flags  ParseFlags
+		Expect bool
+	}{
+		{Input: "1.2.3", Flags: ParseStrictFlags, Expect: true},
+		{Input: "1.2.3-rc.1+build.05", Flags: ParseStrictFlags, Expect: true},
+		{Input: "1.0.0-x-y-z.--", Flags: ParseStrictFlags, Expect: true},
+		{Input: "1.2.3-0A", Flags: ParseStrictFlags, Expect: true},
+		{Input: "", Flags: ParseStrictFlags, Expect: false},
+		{Input: "", Flags: ParseLenientFlags, Expect: false},
+		{Input: "v1.2.3", Flags: ParseStrictFlags, Expect: false},
+		{Input: "v1.2.3", Flags: AllowPrefix, Expect: true},
+		{Input: " v1.2.3", Flags: ParseLenientFlags, Expect: true},
+		{Input: "1.2", Flags: ParseStrictFlags, Expect: false},
+		{Input: "1", Flags: AllowMissing, Expect: true},
+		{Input: "1.", Flags: ParseLenientFlags, Expect: false},
+		{Input: "1.2.3.4", Flags: ParseLenientFlags, Expect: false},
+		{Input: "01.2.3", Flags: ParseStrictFlags, Expect: false},
+		{Input: "1.02.3", Flags: ParseStrictFlags, Expect: false},
+		{Input: "1.2.03", Flags: AllowLeadingZeros, Expect: true},
+		{Input: "1.2.3-rc.01", Flags: ParseStrictFlags, Expect: false},
+		{Input: "1.2.3-rc.01", Flags: AllowLeadingZeros, Expect: true},
+		{Input: "1.2.3-rc..1", Flags: ParseStrictFlags, Expect: false},
+		{Input: "1.2.3-rc.", Flags: ParseStrictFlags, Expect: false},
+		{Input: "1.2.3-", Flags: ParseStrictFlags, Expect: false},
+		{Input: "1.2.3+", Flags: ParseStrictFlags, Expect: false},
+		{Input: "1.2.3-", Flags: AllowEmptyIdents, Expect: true},
+		{Input: "1.2.3-rc.1 (final)", Flags: ParseStrictFlags, Expect: false},
+		{Input: "1.2.3-rc.1 (final)", Flags: AllowTrailing, Expect: true},
+		{Input: "1.2.3-rc_1", Flags: ParseStrictFlags, Expect: false},
+		{Input: "1.2.3 ", Flags: ParseLenientFlags, Expect: false},
+		{Input: "1.2.3x", Flags: ParseLenientFlags, Expect: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.Input, func(t *testing.T) {
+			_, err := ParseVersion(tt.Input, tt.Flags)
+			if ok := err == nil; ok != tt.Expect {
+				t.Fatalf("unexpected parse result for %q with flags %05b: got error: %v, want success: %t", tt.Input, tt.Flags, err, tt.Expect)
+			}
+		})
+	}
+}
+
+func TestParseVersionError(t *testing.T) {
+	tests := []struct {
+		Input  string
+		Flags  ParseFlags
+		Expect ParseError
+	}{
+		{
+			Input:  "",
+			Flags:  ParseLenientFlags,
+			Expect: ParseError{Offset: 0, Token: "", Reason: ReasonMissingComponent},
+		},
+		{
+			Input:  "1.2",
+			Flags:  ParseStrictFlags,
+			Expect: ParseError{Offset: 3, Token: "", Reason: ReasonMissingComponent},
+		},
+		{
+			Input:  "1.2.",
+			Flags:  ParseLenientFlags,
+			Expect: ParseError{Offset: 4, Token: "", Reason: ReasonMissingComponent},
+		},
+		{
+			Input:  "v1.2.3",
+			Flags:  ParseStrictFlags,
+			Expect: ParseError{Offset: 0, Token: "v", Reason: ReasonUnexpectedChar},
+		},
+		{
+			Input:  "1.2.3.4",
+			Flags:  ParseLenientFlags,
+			Expect: ParseError{Offset: 5, Token: ".", Reason: ReasonTooManyComponents},
+		},
+		{
+			Input:  "1.002.3",
+			Flags:  ParseStrictFlags,
+			Expect: ParseError{Offset: 2, Token: "002", Reason: ReasonLeadingZero},
+		},
+		{
+			Input:  "1.2.99999999999999999999",
+			Flags:  ParseLenientFlags,
+			Expect: ParseError{Offset: 4, Token: "99999999999999999999", Reason: ReasonOverflow},
+		},
+		{
+			Input:  "1.2097152.0",
+			Flags:  ParseLenientFlags,
+			Expect: ParseError{Offset: 2, Token: "2097152", Reason: ReasonOverflow},
+		},
+		{
+			Input:  "1.2.3-rc.01",
+			Flags:  ParseStrictFlags,
+			Expect: ParseError{Offset: 9, Token: "01", Reason: ReasonLeadingZero},
+		},
+		{
+			Input:  "1.2.3-rc..1",
+			Flags:  ParseStrictFlags,
+			Expect: ParseError{Offset: 9, Token: "", Reason: ReasonEmptyIdentifier},
+		},
+		{
+			Input:  "1.2.3+build.",
+			Flags:  ParseStrictFlags,
+			Expect: ParseError{Offset: 12, Token: "", Reason: ReasonEmptyIdentifier},
+		},
+		{
+			Input:  "1.2.3-rc_1",
+			Flags:  ParseStrictFlags,
+			Expect: ParseError{Offset: 8, Token: "_", Reason: ReasonUnexpectedChar},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.Input, func(t *testing.T) {
+			_, err := ParseVersion(tt.Input, tt.Flags)
+			if !errors.Is(err, ErrInvalidSemVer) {
+				t.Fatalf("unexpected error: got: %v, want: %v", err, ErrInvalidSemVer)
+			}
+			if overflow := errors.Is(err, ErrOverflow); overflow != (tt.Expect.Reason == ReasonOverflow) {
+				t.Fatalf("unexpected overflow error match for %v: %t", err, overflow)
+			}
+			var perr *ParseError
+			if !errors.As(err, &perr) {
+				t.Fatalf("unexpected error type: got: %T, want: %T", err, perr)
+			}
+			tt.Expect.Input, tt.Expect.Err = tt.Input, ErrInvalidSemVer
+			if !reflect.DeepEqual(*perr, tt.Expect) {
+				t.Fatalf("unexpected parse error: got: %+v, want: %+v", *perr, tt.Expect)
+			}
+		})
+	}
+}
+
+func TestNewVersionRaw(t *testing.T) {
+	tests := []struct {
+		Name      string
+		Input     []uint32
+		ExpectErr error
+		ExpectVer *Version
+	}{
+		{
+			Name:      "in range",
+			Input:     []uint32{5000, 0, MaxComponent},
+			ExpectVer: &Version{base: (5000 << majorShift) | MaxComponent},
+		},
+		{
+			Name:      "major only",
+			Input:     []uint32{7},
+			ExpectVer: &Version{base: 7 << majorShift},
+		},
+		{
+			Name:      "overflow",
+			Input:     []uint32{1, MaxComponent + 1, 0},
+			ExpectErr: ErrOverflow,
+		},
+		{
+			Name:      "too many components",
+			Input:     []uint32{1, 2, 3, 4},
+			ExpectErr: ErrInvalidSemVer,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.Name, func(t *testing.T) {
+			ver, err := NewVersionRaw(tt.Input, "")
+			if !errors.Is(err, tt.ExpectErr) {
+				t.Fatalf("unexpected error: got: %v, want: %v", err, tt.ExpectErr)
+			}
+			if err != nil {
+				return
+			}
+			if !reflect.DeepEqual(ver, tt.ExpectVer) {
+				t.Fatalf("unexpected version: got: %+v, want: %+v", *ver, *tt.ExpectVer)
+			}
+		})
+	}
+}
+
+func TestVersionNext(t *testing.T) {
+	tests := []struct {
+		Name   string
+		Next   *Version
+		Expect *Version
+	}{
+		{
+			Name:   "NextMajor",
+			Next:   newVersionUnsafe("1.2.3-rc.1").NextMajor(),
+			Expect: newVersionUnsafe("2.0.0"),
+		},
+		{
+			Name:   "NextMinor",
+			Next:   newVersionUnsafe("1.2.3").NextMinor(),
+			Expect: newVersionUnsafe("1.3.0"),
+		},
+		{
+			Name:   "NextPatch",
+			Next:   newVersionUnsafe("1.2.3").NextPatch(),
+			Expect: newVersionUnsafe("1.2.4"),
+		},
+		{
+			Name:   "NextMinor carries over",
+			Next:   newVersionUnsafe("1.2097151.3").NextMinor(),
+			Expect: newVersionUnsafe("2.0.0"),
+		},
+		{
+			Name:   "NextPatch carries over",
+			Next:   newVersionUnsafe("1.2097151.2097151").NextPatch(),
+			Expect: newVersionUnsafe("2.0.0"),
+		},
+		{
+			Name:   "NextMajor of the last major",
+			Next:   newVersionUnsafe("2097151.0.0").NextMajor(),
+			Expect: &Version{base: baseInf},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.Name, func(t *testing.T) {
+			if !reflect.DeepEqual(tt.Next, tt.Expect) {
+				t.Fatalf("unexpected version: got: %+v, want: %+v", *tt.Next, *tt.Expect)
+			}
+		})
+	}
+}
+
+func TestVersionFormat(t *testing.T) {
+	v := newVersionUnsafe("v01.2.3-rc.1+build.5")
+	tests := []struct {
+		Format string
+		Expect string
+	}{
+		{Format: "%s", Expect: "1.2.3-rc.1+build.5"},
+		{Format: "%v", Expect: "1.2.3-rc.1+build.5"},
+		{Format: "%q", Expect: `"1.2.3-rc.1+build.5"`},
+		{Format: "%+v", Expect: "1.2.3-rc.1+build.5 (base: 0x0000040000400003)"},
+		{Format: "%#v", Expect: `semver.Version{base:0x0000040000400003, pre:"rc.1", meta:"build.5"}`},
+		{Format: "%20s|", Expect: "  1.2.3-rc.1+build.5|"},
+		{Format: "%-20s|", Expect: "1.2.3-rc.1+build.5  |"},
+		{Format: "%d", Expect: "%!d(semver.Version=1.2.3-rc.1+build.5)"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.Format, func(t *testing.T) {
+			if s := fmt.Sprintf(tt.Format, v); s != tt.Expect {
+				t.Fatalf("unexpected formatted version: got: %s, want: %s", s, tt.Expect)
+			}
+		})
+	}
+
+	if s := v.Prefixed(); s != "v1.2.3-rc.1+build.5" {
+		t.Fatalf("unexpected prefixed version: got: %s, want: %s", s, "v1.2.3-rc.1+build.5")
+	}
+	for _, in := range []string{"0.0.0", "1.2.3", "2024.3.1-rc.1", "120.0.6099+sha.abc123", "1.0.0-x-y.1+git-8c1a"} {
+		if s := newVersionUnsafe(in).String(); s != in {
+			t.Fatalf("unexpected round trip of %q: got: %q", in, s)
+		}
+	}
+}
+
+func TestVersionText(t *testing.T) {
+	type config struct {
+		Version  Version
+		Optional *Version `json:",omitempty"`
+	}
+
+	var c config
+	if err := json.Unmarshal([]byte(`{"Version": "v1.2.3-rc.1+build.5"}`), &c); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if s := c.Version.String(); s != "1.2.3-rc.1+build.5" || c.Optional != nil {
+		t.Fatalf("unexpected decoded config: %+v", c)
+	}
+	c.Optional = newVersionUnsafe("2.0.0")
+	b, err := json.Marshal(c)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if expect := `{"Version":"1.2.3-rc.1+build.5","Optional":"2.0.0"}`; string(b) != expect {
+		t.Fatalf("unexpected encoded config: got: %s, want: %s", b, expect)
+	}
+
+	type manifest struct {
+		V Version
+	}
+	if b, err := xml.Marshal(manifest{*newVersionUnsafe("1.2.3")}); err != nil || string(b) != "<manifest><V>1.2.3</V></manifest>" {
+		t.Fatalf("unexpected encoded xml: %s, error: %v", b, err)
+	}
+
+	err = json.Unmarshal([]byte(`{"Version": "1.2.3.4"}`), &c)
+	var perr *ParseError
+	if !errors.As(err, &perr) || perr.Reason != ReasonTooManyComponents {
+		t.Fatalf("unexpected error: got: %v, want: %s", err, ReasonTooManyComponents)
+	}
+}
+
+func TestVersionSQL(t *testing.T) {
+	var v Version
+	for _, src := range []interface{}{"v1.2.3-rc.1+build.5", []byte("1.2.3-rc.1+build.5")} {
+		if err := v.Scan(src); err != nil {
+			t.Fatalf("unexpected error scanning %v: %v", src, err)
+		}
+		if s := v.String(); s != "1.2.3-rc.1+build.5" {
+			t.Fatalf("unexpected scanned version: got: %s, want: %s", s, "1.2.3-rc.1+build.5")
+		}
+	}
+	if val, err := v.Value(); err != nil || val != "1.2.3-rc.1+build.5" {
+		t.Fatalf("unexpected value: %v, error: %v", val, err)
+	}
+	for _, src := range []interface{}{nil, 123, "1.2.3.4"} {
+		if err := v.Scan(src); !errors.Is(err, ErrInvalidSemVer) {
+			t.Fatalf("unexpected error scanning %v: got: %v, want: %v", src, err, ErrInvalidSemVer)
+		}
+	}
+}
+
+func TestVersionLess(t *testing.T) {
+	// Every version is strictly less than the following one
+	ordered := []string{
+		"1.0.0-alpha",
+		"1.0.0-alpha.1",
+		"1.0.0-alpha.2",
+		"1.0.0-alpha.10",
+		"1.0.0-alpha.beta",
+		"1.0.0-beta",
+		"1.0.0-beta.2",
+		"1.0.0-beta.11",
+		"1.0.0-build.2",
+		"1.0.0-build.10",
+		"1.0.0-build.10.1",
+		"1.0.0-rc.1",
+		"1.0.0",
+		"1.0.1-0",
+		"1.0.1-1",
+		"1.0.1-9",
+		"1.0.1-10",
+		"1.0.1-A",
+		"1.0.1-a",
+		"1.0.1",
+	}
+
+	for i := range ordered {
+		for j := range ordered {
+			v1, v2 := newVersionUnsafe(ordered[i]), newVersionUnsafe(ordered[j])
+			if less := v1.Less(v2); less != (i < j) {
+				t.Errorf("unexpected %s < %s: got: %t, want: %t", ordered[i], ordered[j], less, i < j)
+			}
+			if eq := v1.Equal(v2); eq != (i == j) {
+				t.Errorf("unexpected %s == %s: got: %t, want: %t", ordered[i], ordered[j], eq, i == j)
+			}
+		}
+	}
+}
+
+func TestVersionMetadata(t *testing.T) {
+	v1 := newVersionUnsafe("1.2.3-rc.1+build.5")
+	v2 := newVersionUnsafe("1.2.3-rc.1+build.6")
+	if v1.Metadata() != "build.5" {
+		t.Fatalf("unexpected metadata: got: %q, want: %q", v1.Metadata(), "build.5")
+	}
+	if !v1.Equal(v2) || v1.Less(v2) || v2.Less(v1) {
+		t.Fatalf("build metadata must not affect precedence of %+v and %+v", *v1, *v2)
+	}
+}
+
 func errorEqual(e1, e2 error) bool {
 	if e1 == e2 {
 		return true
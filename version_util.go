@@ -1,45 +1,144 @@
 package semver
 
 import (
-	"fmt"
+	"strings"
 )
 
-func parseVersion(s string) (uint32, string, error) {
+func parseVersion(s string, flags ParseFlags) (uint64, string, string, error) {
 	var ds [3]uint32
 	var d int
-	var pre string
-	var base uint32
+	var pre, meta string
+	var base uint64
+	var suffix bool
+	var reason ParseErrorReason
+	var err error
 	dix := 0
-	i, maxi := 0, len(s)
-	i = skipTrailing(s, i)
-	for i < maxi {
-		if isNum(s[i]) {
+	i, j, maxi := 0, 0, len(s)
+	if flags&AllowPrefix != 0 {
+		i = skipTrailing(s, i)
+	}
+	for {
+		j = i
+		if i >= maxi {
+			reason = ReasonMissingComponent
+			goto Err
+		}
+		if !isNum(s[i]) {
+			j, reason = i+1, ReasonUnexpectedChar
+			goto Err
+		}
+		d, j = readNum(s, i)
+		if d == -1 || d > MaxComponent {
+			reason = ReasonOverflow
+			goto Err
+		}
+		if flags&AllowLeadingZeros == 0 && hasLeadingZero(s, i) {
+			reason = ReasonLeadingZero
+			goto Err
+		}
+		ds[dix] = uint32(d)
+		dix++
+		i = j
+		if i < maxi && isDot(s[i]) {
 			if dix >= len(ds) {
+				j, reason = i+1, ReasonTooManyComponents
 				goto Err
 			}
-			d, i = readNum(s, i)
-			if i == -1 {
-				goto Err
-			}
-			ds[dix] = uint32(d)
-			dix++
-			if i < maxi && isDot(s[i]) {
-				i++
-				continue
-			}
-			if i < maxi && isDash(s[i]) {
-				i++
-				pre, _ = readStr(s, i)
-				break
-			}
-		} else {
-			goto Err
+			i++
+			continue
 		}
+		break
+	}
+	if dix < len(ds) && flags&AllowMissing == 0 {
+		j, reason = i, ReasonMissingComponent
+		goto Err
 	}
 
-	base = (ds[0] << 20) | (ds[1] << 10) | ds[2]
-	return base, pre, nil
+	if i < maxi && isDash(s[i]) {
+		pre, j = readStr(s, i+1)
+		if err = checkIdents(s, i+1, j, true, flags); err != nil {
+			return 0, "", "", err
+		}
+		i, suffix = j, true
+	}
+	if i < maxi && isPlus(s[i]) {
+		meta, j = readStr(s, i+1)
+		if err = checkIdents(s, i+1, j, false, flags); err != nil {
+			return 0, "", "", err
+		}
+		i, suffix = j, true
+	}
+	if i < maxi && !(suffix && flags&AllowTrailing != 0) {
+		j, reason = i+1, ReasonUnexpectedChar
+		goto Err
+	}
+
+	base = (uint64(ds[0]) << majorShift) | (uint64(ds[1]) << minorShift) | uint64(ds[2])
+	return base, pre, meta, nil
 
 Err:
-	return 0, "", fmt.Errorf("failed to parse version: %q", s)
+	return 0, "", "", newParseError(ErrInvalidSemVer, s, i, j, reason)
+}
+
+// checkIdents validates dot-separated identifiers of a pre-release tag
+// (numeric identifiers must not have leading zeros) or of build metadata
+// located in s[i:j].
+func checkIdents(s string, i, j int, pre bool, flags ParseFlags) error {
+	for {
+		id, k := readIdent(s[:j], i)
+		if len(id) == 0 && flags&AllowEmptyIdents == 0 {
+			return newParseError(ErrInvalidSemVer, s, i, k, ReasonEmptyIdentifier)
+		}
+		if pre && flags&AllowLeadingZeros == 0 && isNumStr(id) && hasLeadingZero(id, 0) {
+			return newParseError(ErrInvalidSemVer, s, i, k, ReasonLeadingZero)
+		}
+		if k >= j {
+			return nil
+		}
+		i = k + 1
+	}
+}
+
+// comparePre compares 2 non-empty pre-release tags according to the SemVer
+// 2.0.0 precedence rules: dot-separated identifiers are compared one by one,
+// numeric identifiers numerically, alphanumeric ones in ASCII order, numeric
+// identifiers always have lower precedence than alphanumeric ones, and a
+// shorter list of identifiers is lower if all the preceding ones are equal.
+// Returns -1, 0 or 1.
+func comparePre(p1, p2 string) int {
+	i, j := 0, 0
+	for i < len(p1) && j < len(p2) {
+		id1, ni := readIdent(p1, i)
+		id2, nj := readIdent(p2, j)
+		if c := compareIdent(id1, id2); c != 0 {
+			return c
+		}
+		i, j = ni+1, nj+1
+	}
+	switch {
+	case i < len(p1):
+		return 1
+	case j < len(p2):
+		return -1
+	}
+	return 0
+}
+
+func compareIdent(id1, id2 string) int {
+	num1, num2 := isNumStr(id1), isNumStr(id2)
+	switch {
+	case num1 && num2:
+		id1, id2 = trimZeros(id1), trimZeros(id2)
+		if len(id1) != len(id2) {
+			if len(id1) < len(id2) {
+				return -1
+			}
+			return 1
+		}
+	case num1:
+		return -1
+	case num2:
+		return 1
+	}
+	return strings.Compare(id1, id2)
 }
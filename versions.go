@@ -0,0 +1,145 @@
+package semver
+
+import "sort"
+
+// Versions is a list of versions ordered by precedence, see Version.Less.
+type Versions []*Version
+
+var _ sort.Interface = Versions(nil)
+
+func (vs Versions) Len() int {
+	return len(vs)
+}
+
+func (vs Versions) Less(i, j int) bool {
+	return vs[i].Less(vs[j])
+}
+
+func (vs Versions) Swap(i, j int) {
+	vs[i], vs[j] = vs[j], vs[i]
+}
+
+// radixMin is the length of a list below which sorting by comparison beats
+// the radix sort.
+const radixMin = 256
+
+// Sort sorts the versions in the ascending order of precedence. The sort is
+// stable: versions of equal precedence keep their order. Long lists are
+// radix sorted by the packed numbers first, so that only the versions sharing
+// the numbers are compared by their pre-release tags.
+func (vs Versions) Sort() {
+	if len(vs) < radixMin {
+		sort.Stable(vs)
+		return
+	}
+
+	type entry struct {
+		base uint64
+		v    *Version
+	}
+	es := make([]entry, len(vs))
+	var diff uint64
+	for i, v := range vs {
+		es[i] = entry{v.base, v}
+		diff |= v.base ^ vs[0].base
+	}
+	buf := make([]entry, len(es))
+	for shift := uint(0); shift < 64; shift += 8 {
+		// A byte equal across all the versions does not affect the order
+		if (diff>>shift)&0xFF == 0 {
+			continue
+		}
+		var pos [256]int
+		for _, e := range es {
+			pos[(e.base>>shift)&0xFF]++
+		}
+		n := 0
+		for i, c := range pos {
+			pos[i] = n
+			n += c
+		}
+		for _, e := range es {
+			b := (e.base >> shift) & 0xFF
+			buf[pos[b]] = e
+			pos[b]++
+		}
+		es, buf = buf, es
+	}
+
+	for i := 0; i < len(es); {
+		j := i + 1
+		for j < len(es) && es[j].base == es[i].base {
+			j++
+		}
+		for k := i; k < j; k++ {
+			vs[k] = es[k].v
+		}
+		if j-i > 1 {
+			sort.Stable(vs[i:j])
+		}
+		i = j
+	}
+}
+
+// SortDesc sorts the versions in the descending order of precedence.
+func (vs Versions) SortDesc() {
+	vs.Sort()
+	for i, j := 0, len(vs)-1; i < j; i, j = i+1, j-1 {
+		vs[i], vs[j] = vs[j], vs[i]
+	}
+}
+
+// Dedup sorts the versions and drops the ones equal in precedence to a
+// preceding one: of `1.2.3+a` and `1.2.3+b` only the first one is kept.
+// Returns the shortened list sharing the storage of vs.
+func (vs Versions) Dedup() Versions {
+	vs.Sort()
+	if len(vs) == 0 {
+		return vs
+	}
+	n := 1
+	for _, v := range vs[1:] {
+		if !v.Equal(vs[n-1]) {
+			vs[n] = v
+			n++
+		}
+	}
+	return vs[:n]
+}
+
+// Max returns the greatest version, or nil if the list is empty.
+func (vs Versions) Max() *Version {
+	var max *Version
+	for _, v := range vs {
+		if max == nil || max.Less(v) {
+			max = v
+		}
+	}
+	return max
+}
+
+// Min returns the least version, or nil if the list is empty.
+func (vs Versions) Min() *Version {
+	var min *Version
+	for _, v := range vs {
+		if min == nil || v.Less(min) {
+			min = v
+		}
+	}
+	return min
+}
+
+// Latest returns the greatest version, skipping pre-release versions if
+// stableOnly is set. Returns nil if there is no such version.
+func (vs Versions) Latest(stableOnly bool) *Version {
+	var max *Version
+	for _, v := range vs {
+		if stableOnly && len(v.pre) > 0 {
+			continue
+		}
+		if max == nil || max.Less(v) {
+			max = v
+		}
+	}
+	return max
+}
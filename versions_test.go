@@ -0,0 +1,135 @@
+package semver
+
+import (
+	"math/rand"
+	"reflect"
+	"sort"
+	"strconv"
+	"testing"
+)
+
+func versionStrings(vs Versions) []string {
+	ss := make([]string, 0, len(vs))
+	for _, v := range vs {
+		ss = append(ss, v.String())
+	}
+	return ss
+}
+
+func newVersionsUnsafe(ss ...string) Versions {
+	vs := make(Versions, 0, len(ss))
+	for _, s := range ss {
+		vs = append(vs, newVersionUnsafe(s))
+	}
+	return vs
+}
+
+func TestVersionsSort(t *testing.T) {
+	vs := newVersionsUnsafe("1.10.0", "1.2.3", "1.2.3-rc.1", "0.9.0", "1.2.3-alpha", "2.0.0", "1.2.3+build", "1.2.3-rc.10")
+	vs.Sort()
+	expect := []string{"0.9.0", "1.2.3-alpha", "1.2.3-rc.1", "1.2.3-rc.10", "1.2.3", "1.2.3+build", "1.10.0", "2.0.0"}
+	if ss := versionStrings(vs); !reflect.DeepEqual(ss, expect) {
+		t.Fatalf("unexpected sorted versions: got: %q, want: %q", ss, expect)
+	}
+
+	vs.SortDesc()
+	expect = []string{"2.0.0", "1.10.0", "1.2.3+build", "1.2.3", "1.2.3-rc.10", "1.2.3-rc.1", "1.2.3-alpha", "0.9.0"}
+	if ss := versionStrings(vs); !reflect.DeepEqual(ss, expect) {
+		t.Fatalf("unexpected sorted versions: got: %q, want: %q", ss, expect)
+	}
+}
+
+func TestVersionsRadixSort(t *testing.T) {
+	r := rand.New(rand.NewSource(7))
+	for _, n := range []int{radixMin - 1, radixMin, 5000} {
+		t.Run(strconv.Itoa(n), func(t *testing.T) {
+			vs := make(Versions, 0, n)
+			for i := 0; i < n; i++ {
+				v := newVersionUnsafe(randVersion(r))
+				if r.Intn(2) == 0 {
+					v.base += uint64(r.Intn(MaxComponent)) << majorShift
+				}
+				vs = append(vs, v)
+			}
+			expect := append(Versions(nil), vs...)
+			sort.Stable(expect)
+			vs.Sort()
+			for i := range vs {
+				if vs[i] != expect[i] {
+					t.Fatalf("unexpected version at %d: got: %s, want: %s", i, vs[i], expect[i])
+				}
+			}
+		})
+	}
+}
+
+func TestVersionsDedup(t *testing.T) {
+	vs := newVersionsUnsafe("1.2.3+b", "1.0.0", "1.2.3", "1.2.3+a", "1.0.0", "1.2.3-rc.1")
+	expect := []string{"1.0.0", "1.2.3-rc.1", "1.2.3+b"}
+	if ss := versionStrings(vs.Dedup()); !reflect.DeepEqual(ss, expect) {
+		t.Fatalf("unexpected deduplicated versions: got: %q, want: %q", ss, expect)
+	}
+	if vs := Versions(nil).Dedup(); len(vs) != 0 {
+		t.Fatalf("unexpected deduplicated versions: got: %q, want none", versionStrings(vs))
+	}
+}
+
+func TestVersionsMaxMinLatest(t *testing.T) {
+	tests := []struct {
+		Versions         []string
+		Max, Min, Latest string
+		LatestStable     string
+	}{
+		{
+			Versions:     []string{"1.2.3", "2.0.0-rc.1", "0.9.0", "1.10.0"},
+			Max:          "2.0.0-rc.1",
+			Min:          "0.9.0",
+			Latest:       "2.0.0-rc.1",
+			LatestStable: "1.10.0",
+		},
+		{
+			Versions:     []string{"1.0.0-alpha", "1.0.0-beta"},
+			Max:          "1.0.0-beta",
+			Min:          "1.0.0-alpha",
+			Latest:       "1.0.0-beta",
+			LatestStable: "",
+		},
+		{},
+	}
+
+	str := func(v *Version) string {
+		if v == nil {
+			return ""
+		}
+		return v.String()
+	}
+	for _, tt := range tests {
+		vs := newVersionsUnsafe(tt.Versions...)
+		if s := str(vs.Max()); s != tt.Max {
+			t.Fatalf("unexpected max version of %q: got: %q, want: %q", tt.Versions, s, tt.Max)
+		}
+		if s := str(vs.Min()); s != tt.Min {
+			t.Fatalf("unexpected min version of %q: got: %q, want: %q", tt.Versions, s, tt.Min)
+		}
+		if s := str(vs.Latest(false)); s != tt.Latest {
+			t.Fatalf("unexpected latest version of %q: got: %q, want: %q", tt.Versions, s, tt.Latest)
+		}
+		if s := str(vs.Latest(true)); s != tt.LatestStable {
+			t.Fatalf("unexpected latest stable version of %q: got: %q, want: %q", tt.Versions, s, tt.LatestStable)
+		}
+	}
+}
+
+func BenchmarkVersionsSort(b *testing.B) {
+	r := rand.New(rand.NewSource(8))
+	vs := make(Versions, 0, 50000)
+	for i := 0; i < cap(vs); i++ {
+		vs = append(vs, newVersionRaw([]uint32{uint32(r.Intn(100)), uint32(r.Intn(100)), uint32(r.Intn(100))}, ""))
+	}
+	buf := make(Versions, len(vs))
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		copy(buf, vs)
+		buf.Sort()
+	}
+}